@@ -0,0 +1,89 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"github.com/LubyRuffy/chrome_proxy/chrome_action"
+	"github.com/LubyRuffy/chrome_proxy/models"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+	"log"
+	"strings"
+)
+
+// Archive 生成页面的离线存档，默认为 mhtml，format 为 html 时生成内联了所有资源的单个html文件
+func Archive(options *models.ChromeParam) (*models.ArchiveOutput, error) {
+	return ArchiveContext(context.Background(), options)
+}
+
+// archiveFormat 检查存档格式，默认为 mhtml
+// 存档格式与截图的 format 分开，批量任务中可以同时截图和存档
+func archiveFormat(format string) (string, error) {
+	switch strings.ToLower(format) {
+	case "", models.FormatMHTML:
+		return models.FormatMHTML, nil
+	case models.FormatHTML:
+		return models.FormatHTML, nil
+	}
+	return "", fmt.Errorf("unsupported archive format: %s", format)
+}
+
+// ArchiveContext 生成页面的离线存档，ctx 取消时中止
+func ArchiveContext(ctx context.Context, options *models.ChromeParam) (*models.ArchiveOutput, error) {
+	log.Println("Archive of url:", options.URL)
+
+	actions, finish, err := Actions(options)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := chrome_action.ChromeActionsContext(ctx, options.ChromeActionInput, func(s string, i ...interface{}) {
+
+	}, options.Timeout, nil, actions...)
+	if err != nil {
+		return nil, fmt.Errorf("Archive failed(%w): %s", err, options.URL)
+	}
+	return finish(out)
+}
+
+// Actions 生成页面存档需要在页面中执行的动作，渲染完成后调用 finish 生成结果
+// 用于与其他动作共享同一次渲染
+func Actions(options *models.ChromeParam) ([]chromedp.Action, func(out *models.ChromeActionOutput) (*models.ArchiveOutput, error), error) {
+	format, err := archiveFormat(options.ArchiveFormat)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var mhtml string
+	var actions []chromedp.Action
+	actions = append(actions, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		mhtml, err = page.CaptureSnapshot().WithFormat(page.CaptureSnapshotFormatMhtml).Do(ctx)
+		return err
+	}))
+
+	var title string
+	actions = append(actions, chromedp.Title(&title))
+	var location string
+	actions = append(actions, chromedp.Location(&location))
+
+	return actions, func(out *models.ChromeActionOutput) (*models.ArchiveOutput, error) {
+		data := mhtml
+		if format == models.FormatHTML {
+			var err error
+			data, err = MHTMLToHTML(mhtml)
+			if err != nil {
+				return nil, fmt.Errorf("convert mhtml failed(%w): %s", err, options.URL)
+			}
+		}
+
+		return &models.ArchiveOutput{
+			Data:     data,
+			Format:   format,
+			Title:    title,
+			Location: location,
+
+			ChromeActionOutput: *out,
+		}, nil
+	}, nil
+}
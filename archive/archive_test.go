@@ -0,0 +1,31 @@
+package archive
+
+import (
+	"github.com/LubyRuffy/chrome_proxy/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestArchiveFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{name: "测试默认格式", format: "", want: models.FormatMHTML},
+		{name: "测试html", format: "HTML", want: models.FormatHTML},
+		{name: "测试截图格式", format: models.FormatPNG, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := archiveFormat(tt.format)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	// htmlRefRegexp html 中 src、href 属性引用的资源
+	htmlRefRegexp = regexp.MustCompile(`(?i)(\s(?:src|href)\s*=\s*)("[^"]*"|'[^']*')`)
+	// cssRefRegexp css 中 url() 引用的资源
+	cssRefRegexp = regexp.MustCompile(`(?i)url\(\s*("[^"]*"|'[^']*'|[^)'"\s]*)\s*\)`)
+)
+
+// resource mhtml 中的一个资源
+type resource struct {
+	contentType string
+	location    string
+	data        []byte
+
+	// dataURI 内联后的 data uri，为空表示还没有处理
+	dataURI string
+	// inlining 正在处理中，用于避免循环引用
+	inlining bool
+}
+
+// archive 解析后的 mhtml
+type archive struct {
+	main      *resource
+	resources map[string]*resource
+}
+
+// parseMHTML 解析 chrome 生成的 mhtml，第一个部分为主页面
+func parseMHTML(mhtml string) (*archive, error) {
+	msg, err := mail.ReadMessage(strings.NewReader(mhtml))
+	if err != nil {
+		return nil, err
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		return nil, errors.New("mhtml is not multipart")
+	}
+
+	a := &archive{resources: make(map[string]*resource)}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// quoted-printable 由 multipart 自动解码，base64 需要手动解码
+		var r io.Reader = p
+		if strings.EqualFold(p.Header.Get("Content-Transfer-Encoding"), "base64") {
+			r = base64.NewDecoder(base64.StdEncoding, p)
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+
+		res := &resource{
+			contentType: p.Header.Get("Content-Type"),
+			location:    p.Header.Get("Content-Location"),
+			data:        data,
+		}
+		if a.main == nil {
+			a.main = res
+		}
+		if res.location != "" {
+			a.resources[res.location] = res
+		}
+		if id := strings.Trim(p.Header.Get("Content-ID"), "<>"); id != "" {
+			a.resources["cid:"+id] = res
+		}
+	}
+
+	if a.main == nil {
+		return nil, errors.New("mhtml is empty")
+	}
+	return a, nil
+}
+
+// lookup 以 base 为基准解析引用地址，并查找对应的资源
+func (a *archive) lookup(base, ref string) *resource {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.HasPrefix(ref, "data:") || strings.HasPrefix(ref, "#") {
+		return nil
+	}
+	if res, ok := a.resources[ref]; ok {
+		return res
+	}
+
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return nil
+	}
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return nil
+	}
+	abs := baseURL.ResolveReference(refURL)
+	abs.Fragment = ""
+	return a.resources[abs.String()]
+}
+
+// isHTML 判断资源是否为 html（主页面或者 iframe）
+func (r *resource) isHTML() bool {
+	return strings.HasPrefix(r.contentType, "text/html")
+}
+
+// isCSS 判断资源是否为样式表
+func (r *resource) isCSS() bool {
+	return strings.HasPrefix(r.contentType, "text/css")
+}
+
+// inline 将资源中引用的其他资源替换为 data uri，返回替换后的内容
+func (a *archive) inline(r *resource) []byte {
+	switch {
+	case r.isHTML():
+		data := htmlRefRegexp.ReplaceAllFunc(r.data, func(m []byte) []byte {
+			sub := htmlRefRegexp.FindSubmatch(m)
+			quote := sub[2][0]
+			ref := string(sub[2][1 : len(sub[2])-1])
+			if uri := a.dataURI(r.location, ref); uri != "" {
+				return []byte(string(sub[1]) + string(quote) + uri + string(quote))
+			}
+			return m
+		})
+		return a.inlineCSS(r.location, data)
+	case r.isCSS():
+		return a.inlineCSS(r.location, r.data)
+	}
+	return r.data
+}
+
+// inlineCSS 替换 css（包括 html 中的 style）中 url() 引用的资源
+func (a *archive) inlineCSS(base string, data []byte) []byte {
+	return cssRefRegexp.ReplaceAllFunc(data, func(m []byte) []byte {
+		ref := string(cssRefRegexp.FindSubmatch(m)[1])
+		ref = strings.Trim(ref, `"'`)
+		if uri := a.dataURI(base, ref); uri != "" {
+			return []byte(`url("` + uri + `")`)
+		}
+		return m
+	})
+}
+
+// dataURI 返回引用资源内联后的 data uri，资源不存在时返回空
+func (a *archive) dataURI(base, ref string) string {
+	res := a.lookup(base, ref)
+	if res == nil || res.inlining {
+		return ""
+	}
+	if res.dataURI == "" {
+		res.inlining = true
+		data := a.inline(res)
+		res.inlining = false
+
+		contentType := res.contentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		res.dataURI = "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(data)
+	}
+	return res.dataURI
+}
+
+// MHTMLToHTML 将 mhtml 转换为单个html文件，图片、样式、字体、iframe 等资源均内联为 data uri
+func MHTMLToHTML(mhtml string) (string, error) {
+	a, err := parseMHTML(mhtml)
+	if err != nil {
+		return "", err
+	}
+
+	a.main.inlining = true
+	data := a.inline(a.main)
+	return string(bytes.TrimSpace(data)), nil
+}
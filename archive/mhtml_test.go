@@ -0,0 +1,76 @@
+package archive
+
+import (
+	"encoding/base64"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// sampleMHTML chrome 生成的 mhtml 的简化版本
+var sampleMHTML = strings.ReplaceAll(`From: <Saved by Blink>
+Snapshot-Content-Location: https://example.com/index.html
+Subject: Example
+MIME-Version: 1.0
+Content-Type: multipart/related;
+	type="text/html";
+	boundary="----MultipartBoundary--abc----"
+
+
+------MultipartBoundary--abc----
+Content-Type: text/html
+Content-ID: <frame-1@mhtml.blink>
+Content-Transfer-Encoding: quoted-printable
+Content-Location: https://example.com/index.html
+
+<html><head><link rel=3D"stylesheet" href=3D"css/style.css"></head><body>=
+<img src=3D"/logo.png"><a href=3D"#top">top</a><img src=3D"https://cdn.exam=
+ple.com/missing.png"><iframe src=3D"cid:frame-2@mhtml.blink"></iframe></body></html>
+------MultipartBoundary--abc----
+Content-Type: text/css
+Content-Transfer-Encoding: quoted-printable
+Content-Location: https://example.com/css/style.css
+
+body { background: url(../bg.png); }
+------MultipartBoundary--abc----
+Content-Type: image/png
+Content-Transfer-Encoding: base64
+Content-Location: https://example.com/logo.png
+
+bG9nbw==
+------MultipartBoundary--abc----
+Content-Type: image/png
+Content-Transfer-Encoding: base64
+Content-Location: https://example.com/bg.png
+
+Ymc=
+------MultipartBoundary--abc----
+Content-Type: text/html
+Content-ID: <frame-2@mhtml.blink>
+Content-Transfer-Encoding: quoted-printable
+
+<p>frame</p>
+------MultipartBoundary--abc------
+`, "\n", "\r\n")
+
+func TestMHTMLToHTML(t *testing.T) {
+	got, err := MHTMLToHTML(sampleMHTML)
+	assert.Nil(t, err)
+
+	b64 := base64.StdEncoding.EncodeToString
+	// 图片
+	assert.Contains(t, got, `<img src="data:image/png;base64,`+b64([]byte("logo"))+`">`)
+	// 样式表中引用的图片同样内联
+	css := `body { background: url("data:image/png;base64,` + b64([]byte("bg")) + `"); }`
+	assert.Contains(t, got, `href="data:text/css;base64,`+b64([]byte(css))+`"`)
+	// iframe
+	assert.Contains(t, got, `<iframe src="data:text/html;base64,`+b64([]byte("<p>frame</p>"))+`">`)
+	// 锚点和不存在的资源保持不变
+	assert.Contains(t, got, `<a href="#top">`)
+	assert.Contains(t, got, `<img src="https://cdn.example.com/missing.png">`)
+}
+
+func TestMHTMLToHTML_Invalid(t *testing.T) {
+	_, err := MHTMLToHTML("Content-Type: text/html\r\n\r\n<html></html>")
+	assert.Error(t, err)
+}
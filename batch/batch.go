@@ -0,0 +1,134 @@
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/LubyRuffy/chrome_proxy/limiter"
+	"github.com/LubyRuffy/chrome_proxy/models"
+	"github.com/LubyRuffy/chrome_proxy/task"
+	"net/http"
+	"sync"
+)
+
+// Param 批量任务参数
+// 外层字段作为所有目标的默认值，每个目标可以覆盖其中任意字段
+type Param struct {
+	models.ChromeParam
+	Targets []json.RawMessage `json:"targets"`
+	Actions []string          `json:"actions"`
+}
+
+// GetParamFromRequest 从请求中读取批量任务参数
+func GetParamFromRequest(r *http.Request) (*Param, error) {
+	var p Param
+	err := json.NewDecoder(r.Body).Decode(&p)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+
+	if len(p.Targets) == 0 {
+		return nil, errors.New("no targets")
+	}
+	if len(p.Actions) == 0 {
+		p.Actions = []string{models.ActionScreenshot}
+	}
+	for _, action := range p.Actions {
+		if !task.Valid(action) {
+			return nil, fmt.Errorf("unknown action: %s", action)
+		}
+	}
+	return &p, nil
+}
+
+// Options 将默认参数与每个目标的参数合并
+// 目标既可以是完整的参数对象，也可以只是一个url字符串
+func (p *Param) Options() ([]*models.ChromeParam, error) {
+	// 每个目标都从默认值重新解码，避免 slice/map 类型的字段在目标间共享
+	defaults, err := json.Marshal(p.ChromeParam)
+	if err != nil {
+		return nil, err
+	}
+
+	var options []*models.ChromeParam
+	for i, raw := range p.Targets {
+		var o models.ChromeParam
+		if err = json.Unmarshal(defaults, &o); err != nil {
+			return nil, err
+		}
+
+		var url string
+		if err = json.Unmarshal(raw, &url); err == nil {
+			o.URL = url
+		} else if err = json.Unmarshal(raw, &o); err != nil {
+			return nil, fmt.Errorf("invalid target %d: %w", i, err)
+		}
+
+		if o.URL == "" {
+			return nil, fmt.Errorf("invalid target %d: url is empty", i)
+		}
+		if o.Timeout == 0 {
+			o.Timeout = 20
+		}
+		options = append(options, &o)
+	}
+	return options, nil
+}
+
+// Run 对所有目标执行所有动作，单个目标失败不影响其他目标
+// 同一个目标的所有动作在一次渲染中完成，每个目标需要从 l 获取一个执行槽位，结果顺序与目标顺序一致
+// ctx 取消（例如客户端断开）时中止剩余的目标
+func Run(ctx context.Context, l *limiter.Limiter, options []*models.ChromeParam, actions []string) []models.TargetResult {
+	results := make([]models.TargetResult, len(options))
+
+	type job struct {
+		index   int
+		options *models.ChromeParam
+	}
+	jobs := make(chan job)
+
+	// worker数量不超过限制器的并发数，避免一次批量任务占满等待队列
+	workers := l.Cap()
+	if workers > len(results) {
+		workers = len(results)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				results[j.index] = runOne(ctx, l, actions, j.options)
+			}
+		}()
+	}
+
+	for i, o := range options {
+		jobs <- job{index: i, options: o}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+func runOne(ctx context.Context, l *limiter.Limiter, actions []string, options *models.ChromeParam) models.TargetResult {
+	if err := l.Acquire(ctx); err != nil {
+		target := models.TargetResult{Url: options.URL}
+		for _, action := range actions {
+			target.Results = append(target.Results, models.Result{
+				Code:    http.StatusTooManyRequests,
+				Url:     options.URL,
+				Message: err.Error(),
+				Action:  action,
+			})
+		}
+		return target
+	}
+	defer l.Release()
+
+	return task.RunAll(ctx, actions, options)
+}
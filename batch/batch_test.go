@@ -0,0 +1,115 @@
+package batch
+
+import (
+	"context"
+	"github.com/LubyRuffy/chrome_proxy/limiter"
+	"github.com/LubyRuffy/chrome_proxy/models"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParam_Options(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    []*models.ChromeParam
+		wantErr bool
+	}{
+		{
+			name: "测试默认参数与覆盖参数",
+			body: `{"sleep":1,"timeout":10,"proxy":"socks5://127.0.0.1:7890","targets":["https://fofa.info",{"url":"https://www.baidu.com","sleep":3,"proxy":""}]}`,
+			want: []*models.ChromeParam{
+				{ChromeActionInput: models.ChromeActionInput{URL: "https://fofa.info", Proxy: "socks5://127.0.0.1:7890", Sleep: 1, Timeout: 10}},
+				{ChromeActionInput: models.ChromeActionInput{URL: "https://www.baidu.com", Sleep: 3, Timeout: 10}},
+			},
+		},
+		{
+			name: "测试默认超时时间",
+			body: `{"targets":[{"url":"https://fofa.info","add_url":true}],"actions":["screenshot","dom"]}`,
+			want: []*models.ChromeParam{
+				{AddUrl: true, ChromeActionInput: models.ChromeActionInput{URL: "https://fofa.info", Timeout: 20}},
+			},
+		},
+		{
+			name:    "测试缺少url",
+			body:    `{"targets":[{"sleep":1}]}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := GetParamFromRequest(httptest.NewRequest("POST", "/batch", strings.NewReader(tt.body)))
+			assert.Nil(t, err)
+
+			got, err := p.Options()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGetParamFromRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantActions []string
+		wantErr     bool
+	}{
+		{
+			name:        "测试默认动作",
+			body:        `{"targets":["https://fofa.info"]}`,
+			wantActions: []string{models.ActionScreenshot},
+		},
+		{
+			name:    "测试未知动作",
+			body:    `{"targets":["https://fofa.info"],"actions":["unknown"]}`,
+			wantErr: true,
+		},
+		{
+			name:    "测试空目标",
+			body:    `{"targets":[]}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := GetParamFromRequest(httptest.NewRequest("POST", "/batch", strings.NewReader(tt.body)))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.wantActions, p.Actions)
+		})
+	}
+}
+
+func TestRun_busy(t *testing.T) {
+	// 占满唯一的执行槽位且不允许排队，所有目标都返回繁忙
+	l := limiter.New(1, 0, 0)
+	assert.Nil(t, l.Acquire(context.Background()))
+	defer l.Release()
+
+	options := []*models.ChromeParam{
+		{ChromeActionInput: models.ChromeActionInput{URL: "https://fofa.info"}},
+		{ChromeActionInput: models.ChromeActionInput{URL: "https://www.baidu.com"}},
+	}
+	results := Run(context.Background(), l, options, []string{models.ActionScreenshot, models.ActionDom})
+
+	// 每个 url 一条结果，其中包含每个动作的结果
+	assert.Len(t, results, 2)
+	for i, r := range results {
+		assert.Equal(t, options[i].URL, r.Url)
+		assert.Len(t, r.Results, 2)
+		assert.Equal(t, models.ActionScreenshot, r.Results[0].Action)
+		assert.Equal(t, models.ActionDom, r.Results[1].Action)
+		assert.Equal(t, http.StatusTooManyRequests, r.Results[0].Code)
+	}
+}
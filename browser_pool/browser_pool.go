@@ -0,0 +1,221 @@
+package browser_pool
+
+import (
+	"context"
+	"errors"
+	"github.com/chromedp/chromedp"
+	"log"
+	"os"
+	"sync"
+)
+
+// ErrPoolClosed 浏览器池已关闭
+var ErrPoolClosed = errors.New("browser pool closed")
+
+// Stats 浏览器池状态
+type Stats struct {
+	Size int `json:"size"`
+	Idle int `json:"idle"`
+	Busy int `json:"busy"`
+	Dead int `json:"dead"` // 重启失败、等待下次使用时重新启动的槽位
+}
+
+// Pool 常驻浏览器池，避免每个请求都冷启动chrome
+// 每个浏览器同一时间只服务一个请求，请求之间通过独立的隐身上下文隔离
+type Pool struct {
+	size      int
+	maxUses   int
+	allocOpts []chromedp.ExecAllocatorOption
+
+	// idle 空闲槽位，nil 表示该槽位的浏览器需要重新启动
+	idle chan *browser
+	// done 关闭时关闭，唤醒等待空闲槽位的调用方
+	done chan struct{}
+
+	mu     sync.Mutex
+	busy   int
+	dead   int // idle 中 nil 槽位的数量
+	closed bool
+}
+
+type browser struct {
+	allocCancel context.CancelFunc
+	ctx         context.Context
+	cancel      context.CancelFunc
+	uses        int
+}
+
+// New 启动 size 个常驻浏览器
+// maxUses 为单个浏览器最多服务的请求数，超过后回收重启，0表示不限制
+func New(size, maxUses int, allocOpts ...chromedp.ExecAllocatorOption) (*Pool, error) {
+	if size <= 0 {
+		return nil, errors.New("browser pool size must be greater than 0")
+	}
+
+	p := &Pool{
+		size:      size,
+		maxUses:   maxUses,
+		allocOpts: allocOpts,
+		idle:      make(chan *browser, size),
+		done:      make(chan struct{}),
+	}
+
+	for i := 0; i < size; i++ {
+		b, err := newBrowser(allocOpts)
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		p.idle <- b
+	}
+	return p, nil
+}
+
+func newBrowser(allocOpts []chromedp.ExecAllocatorOption) (*browser, error) {
+	allocCtx, allocCancel := chromedp.NewExecAllocator(context.Background(), allocOpts...)
+	ctx, cancel := chromedp.NewContext(allocCtx)
+
+	// 空的 Run 会直接拉起浏览器进程
+	if err := chromedp.Run(ctx); err != nil {
+		cancel()
+		allocCancel()
+		return nil, err
+	}
+
+	return &browser{
+		allocCancel: allocCancel,
+		ctx:         ctx,
+		cancel:      cancel,
+	}, nil
+}
+
+// alive 浏览器连接断开（崩溃）后 chromedp 会取消其上下文
+func (b *browser) alive() bool {
+	return b.ctx.Err() == nil
+}
+
+func (b *browser) close() {
+	c := chromedp.FromContext(b.ctx)
+	b.cancel()
+	b.allocCancel()
+	if c != nil && c.Browser != nil && c.Browser.Process() != nil {
+		c.Browser.Process().Signal(os.Kill)
+	}
+}
+
+// Acquire 从池中取出一个浏览器，并在其中创建新的隐身上下文（BrowserContext）
+// 调用方必须调用返回的 release，用于关闭上下文并归还浏览器
+func (p *Pool) Acquire(ctx context.Context, opts ...chromedp.CreateBrowserContextOption) (context.Context, context.CancelFunc, error) {
+	select {
+	case <-p.done:
+		return nil, nil, ErrPoolClosed
+	default:
+	}
+
+	var b *browser
+	select {
+	case b = <-p.idle:
+	case <-p.done:
+		return nil, nil, ErrPoolClosed
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		p.idle <- b
+		return nil, nil, ErrPoolClosed
+	}
+	p.busy++
+	if b == nil {
+		p.dead--
+	}
+	p.mu.Unlock()
+
+	// 崩溃或者启动失败的槽位，在这里重新启动
+	if b == nil || !b.alive() {
+		if b != nil {
+			b.close()
+		}
+		var err error
+		b, err = newBrowser(p.allocOpts)
+		if err != nil {
+			p.put(nil)
+			return nil, nil, err
+		}
+	}
+
+	tabCtx, tabCancel := chromedp.NewContext(b.ctx, chromedp.WithNewBrowserContext(opts...))
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			tabCancel()
+			b.uses++
+			if b.alive() && (p.maxUses <= 0 || b.uses < p.maxUses) {
+				p.put(b)
+				return
+			}
+
+			// 达到使用次数上限或已崩溃，后台重启以保持浏览器常驻
+			go func() {
+				b.close()
+				nb, err := newBrowser(p.allocOpts)
+				if err != nil {
+					log.Println("[WARNING] restart browser failed:", err)
+				}
+				p.put(nb)
+			}()
+		})
+	}
+	return tabCtx, release, nil
+}
+
+// put 归还槽位，idle 的容量与槽位数相同，持锁发送不会阻塞
+func (p *Pool) put(b *browser) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.busy--
+	if b == nil {
+		p.dead++
+	}
+	p.idle <- b
+}
+
+// Stats 返回浏览器池大小以及空闲、忙碌、需要重启的数量
+func (p *Pool) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Acquire 取出槽位后才持锁更新 dead，期间可能短暂小于0
+	idle := len(p.idle) - p.dead
+	if idle < 0 {
+		idle = 0
+	}
+	return Stats{
+		Size: p.size,
+		Idle: idle,
+		Busy: p.busy,
+		Dead: p.dead,
+	}
+}
+
+// Close 等待所有浏览器归还后关闭
+func (p *Pool) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.done)
+	n := p.size - p.busy - len(p.idle)
+	p.mu.Unlock()
+
+	// New 中途失败时，部分槽位从未被填充
+	for i := 0; i < p.size-n; i++ {
+		if b := <-p.idle; b != nil {
+			b.close()
+		}
+	}
+}
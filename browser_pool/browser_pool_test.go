@@ -0,0 +1,121 @@
+package browser_pool
+
+import (
+	"context"
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	type args struct {
+		size      int
+		maxUses   int
+		allocOpts []chromedp.ExecAllocatorOption
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name:    "测试池大小为0",
+			args:    args{size: 0},
+			wantErr: true,
+		},
+		{
+			name: "测试chrome启动失败",
+			args: args{
+				size:      2,
+				allocOpts: []chromedp.ExecAllocatorOption{chromedp.ExecPath("/nonexistent/chrome")},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := New(tt.args.size, tt.args.maxUses, tt.args.allocOpts...)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, p)
+				return
+			}
+			assert.Nil(t, err)
+			p.Close()
+		})
+	}
+}
+
+func TestPool_Acquire(t *testing.T) {
+	p, err := New(2, 2)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer p.Close()
+
+	assert.Equal(t, Stats{Size: 2, Idle: 2, Busy: 0}, p.Stats())
+
+	ctx, release, err := p.Acquire(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, Stats{Size: 2, Idle: 1, Busy: 1}, p.Stats())
+
+	var title string
+	err = chromedp.Run(ctx, chromedp.Navigate("about:blank"), chromedp.Title(&title))
+	assert.Nil(t, err)
+	release()
+	assert.Equal(t, 0, p.Stats().Busy)
+}
+
+func TestPool_Close_wakeWaiters(t *testing.T) {
+	// 唯一的浏览器被占用，没有空闲槽位
+	p := &Pool{
+		size: 1,
+		busy: 1,
+		idle: make(chan *browser, 1),
+		done: make(chan struct{}),
+	}
+
+	errCh := make(chan error)
+	go func() {
+		_, _, err := p.Acquire(context.Background())
+		errCh <- err
+	}()
+
+	closed := make(chan struct{})
+	go func() {
+		p.Close()
+		close(closed)
+	}()
+
+	select {
+	case err := <-errCh:
+		assert.Equal(t, ErrPoolClosed, err)
+	case <-time.After(time.Second):
+		t.Fatal("Acquire not woken by Close")
+	}
+
+	// 归还占用的浏览器后 Close 结束
+	p.put(nil)
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close not finished")
+	}
+
+	_, _, err := p.Acquire(context.Background())
+	assert.Equal(t, ErrPoolClosed, err)
+}
+
+func TestPool_Stats_dead(t *testing.T) {
+	// 唯一的浏览器被占用，归还时重启失败
+	p := &Pool{
+		size: 1,
+		busy: 1,
+		idle: make(chan *browser, 1),
+		done: make(chan struct{}),
+	}
+
+	p.put(nil)
+	assert.Equal(t, Stats{Size: 1, Idle: 0, Busy: 0, Dead: 1}, p.Stats())
+}
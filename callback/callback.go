@@ -0,0 +1,92 @@
+package callback
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/LubyRuffy/chrome_proxy/models"
+	"log"
+	"net/http"
+	"time"
+)
+
+// SignatureHeader 回调请求中签名所在的 header
+const SignatureHeader = "X-Chrome-Proxy-Signature"
+
+// Sender 回调发送器，把结果以 json 格式 POST 到回调地址
+type Sender struct {
+	// Secret 用于计算 HMAC-SHA256 签名，为空时不签名
+	Secret string
+	// MaxRetries 失败后的最大重试次数
+	MaxRetries int
+	// Backoff 第一次重试前的等待时间，之后每次翻倍
+	Backoff time.Duration
+	Client  *http.Client
+}
+
+// DefaultSender 默认回调发送器
+var DefaultSender = &Sender{
+	MaxRetries: 5,
+	Backoff:    time.Second,
+	Client:     &http.Client{Timeout: 10 * time.Second},
+}
+
+// Sign 计算 body 的签名，格式为 sha256=<hex>
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Send 发送回调，失败时按指数退避重试
+func (s *Sender) Send(ctx context.Context, url string, result models.Result) error {
+	body := result.Bytes()
+
+	var err error
+	backoff := s.Backoff
+	for i := 0; i <= s.MaxRetries; i++ {
+		if i > 0 {
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			backoff *= 2
+		}
+
+		if err = s.post(ctx, url, body); err == nil {
+			return nil
+		}
+		log.Printf("[WARNING] callback to %s failed(%d/%d): %s", url, i+1, s.MaxRetries+1, err)
+	}
+	return err
+}
+
+func (s *Sender) post(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(s.Secret, body))
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package callback
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/LubyRuffy/chrome_proxy/models"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSender_Send(t *testing.T) {
+	tests := []struct {
+		name       string
+		failTimes  int
+		maxRetries int
+		wantCalls  int
+		wantErr    bool
+	}{
+		{
+			name:       "测试一次成功",
+			failTimes:  0,
+			maxRetries: 2,
+			wantCalls:  1,
+		},
+		{
+			name:       "测试重试后成功",
+			failTimes:  2,
+			maxRetries: 2,
+			wantCalls:  3,
+		},
+		{
+			name:       "测试重试次数用尽",
+			failTimes:  3,
+			maxRetries: 1,
+			wantCalls:  2,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				body, _ := io.ReadAll(r.Body)
+				assert.Equal(t, Sign("secret", body), r.Header.Get(SignatureHeader))
+
+				var result models.Result
+				assert.Nil(t, json.Unmarshal(body, &result))
+				assert.Equal(t, "https://fofa.info", result.Url)
+
+				if calls <= tt.failTimes {
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			}))
+			defer ts.Close()
+
+			s := &Sender{
+				Secret:     "secret",
+				MaxRetries: tt.maxRetries,
+				Backoff:    time.Millisecond,
+			}
+			err := s.Send(context.Background(), ts.URL, models.Result{Code: 200, Url: "https://fofa.info"})
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+			assert.Equal(t, tt.wantCalls, calls)
+		})
+	}
+}
+
+func TestSign(t *testing.T) {
+	assert.Equal(t, "sha256=f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8",
+		Sign("key", []byte("The quick brown fox jumps over the lazy dog")))
+}
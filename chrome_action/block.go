@@ -0,0 +1,96 @@
+package chrome_action
+
+import (
+	"fmt"
+	"github.com/LubyRuffy/chrome_proxy/models"
+	"github.com/chromedp/cdproto/network"
+	"regexp"
+	"strings"
+)
+
+// resourceTypes 可以拦截的资源类型
+var resourceTypes = map[string]network.ResourceType{}
+
+func init() {
+	for _, t := range []network.ResourceType{
+		network.ResourceTypeDocument,
+		network.ResourceTypeStylesheet,
+		network.ResourceTypeImage,
+		network.ResourceTypeMedia,
+		network.ResourceTypeFont,
+		network.ResourceTypeScript,
+		network.ResourceTypeTextTrack,
+		network.ResourceTypeXHR,
+		network.ResourceTypeFetch,
+		network.ResourceTypePrefetch,
+		network.ResourceTypeEventSource,
+		network.ResourceTypeWebSocket,
+		network.ResourceTypeManifest,
+		network.ResourceTypeSignedExchange,
+		network.ResourceTypePing,
+		network.ResourceTypeCSPViolationReport,
+		network.ResourceTypePreflight,
+		network.ResourceTypeOther,
+	} {
+		resourceTypes[strings.ToLower(string(t))] = t
+	}
+}
+
+// blockRules 请求拦截规则
+type blockRules struct {
+	types    map[network.ResourceType]bool
+	patterns []*regexp.Regexp
+}
+
+// newBlockRules 没有配置拦截时返回 nil
+func newBlockRules(in models.ChromeActionInput) (*blockRules, error) {
+	if len(in.BlockResourceTypes) == 0 && len(in.BlockURLPatterns) == 0 {
+		return nil, nil
+	}
+
+	rules := &blockRules{types: make(map[network.ResourceType]bool)}
+	for _, name := range in.BlockResourceTypes {
+		t, ok := resourceTypes[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown resource type: %s", name)
+		}
+		rules.types[t] = true
+	}
+	for _, pattern := range in.BlockURLPatterns {
+		re, err := compileURLPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		rules.patterns = append(rules.patterns, re)
+	}
+	return rules, nil
+}
+
+// compileURLPattern 以 / 开头和结尾的为正则表达式，其他为完整匹配 url 的通配符，* 匹配任意字符，? 匹配单个字符
+func compileURLPattern(pattern string) (*regexp.Regexp, error) {
+	if len(pattern) >= 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid url pattern %s: %w", pattern, err)
+		}
+		return re, nil
+	}
+
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.ReplaceAll(expr, `\*`, `.*`)
+	expr = strings.ReplaceAll(expr, `\?`, `.`)
+	return regexp.MustCompile("^" + expr + "$"), nil
+}
+
+// match 判断请求是否需要拦截
+func (r *blockRules) match(url string, t network.ResourceType) bool {
+	if r.types[t] {
+		return true
+	}
+	for _, re := range r.patterns {
+		if re.MatchString(url) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package chrome_action
+
+import (
+	"github.com/LubyRuffy/chrome_proxy/models"
+	"github.com/chromedp/cdproto/network"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCompileURLPattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		match   []string
+		noMatch []string
+		wantErr bool
+	}{
+		{
+			name:    "测试通配符",
+			pattern: "*://*.doubleclick.net/*",
+			match:   []string{"https://ad.doubleclick.net/x.js", "http://a.b.doubleclick.net/"},
+			noMatch: []string{"https://doubleclick.net.evil.com/", "https://example.com/?u=doubleclick.net"},
+		},
+		{
+			name:    "测试单字符通配符",
+			pattern: "https://example.com/v?.js",
+			match:   []string{"https://example.com/v1.js"},
+			noMatch: []string{"https://example.com/v10.js"},
+		},
+		{
+			name:    "测试点号不作为正则",
+			pattern: "https://a.com/*",
+			noMatch: []string{"https://abcom/x"},
+		},
+		{
+			name:    "测试正则",
+			pattern: `/\.(mp4|webm)(\?|$)/`,
+			match:   []string{"https://a.com/v.mp4", "https://a.com/v.webm?t=1"},
+			noMatch: []string{"https://a.com/v.mp4.html"},
+		},
+		{
+			name:    "测试错误正则",
+			pattern: "/(/",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			re, err := compileURLPattern(tt.pattern)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			for _, u := range tt.match {
+				assert.True(t, re.MatchString(u), u)
+			}
+			for _, u := range tt.noMatch {
+				assert.False(t, re.MatchString(u), u)
+			}
+		})
+	}
+}
+
+func TestBlockRules_match(t *testing.T) {
+	rules, err := newBlockRules(models.ChromeActionInput{})
+	assert.Nil(t, err)
+	assert.Nil(t, rules)
+
+	rules, err = newBlockRules(models.ChromeActionInput{
+		BlockResourceTypes: []string{"Image", "media"},
+		BlockURLPatterns:   []string{"*google-analytics.com*"},
+	})
+	assert.Nil(t, err)
+	assert.True(t, rules.match("https://a.com/logo.png", network.ResourceTypeImage))
+	assert.True(t, rules.match("https://www.google-analytics.com/analytics.js", network.ResourceTypeScript))
+	assert.False(t, rules.match("https://a.com/app.js", network.ResourceTypeScript))
+}
@@ -2,19 +2,21 @@ package chrome_action
 
 import (
 	"context"
-	"errors"
+	"github.com/LubyRuffy/chrome_proxy/browser_pool"
+	"github.com/LubyRuffy/chrome_proxy/har"
 	"github.com/LubyRuffy/chrome_proxy/models"
+	"github.com/chromedp/cdproto/target"
 	"github.com/chromedp/chromedp"
-	"log"
 	"os"
 	"strings"
 	"time"
 )
 
-// ChromeActions 完成chrome的headless操作
-func ChromeActions(in models.ChromeActionInput, logf func(string, ...interface{}), timeout int, preActions []chromedp.Action, actions ...chromedp.Action) error {
-	var err error
+// Pool 全局浏览器池，为 nil 时每次调用都单独启动一个 chrome
+var Pool *browser_pool.Pool
 
+// ExecAllocatorOptions 生成 chrome 的启动参数
+func ExecAllocatorOptions(in models.ChromeActionInput) []chromedp.ExecAllocatorOption {
 	// set user-agent
 	if in.UserAgent == "" {
 		in.UserAgent = models.DefaultUserAgent
@@ -40,8 +42,14 @@ func ChromeActions(in models.ChromeActionInput, logf func(string, ...interface{}
 	)
 
 	// set proxy if exists
-	if in.Proxy != "" {
+	// 有按主机选择代理的规则时使用 PAC 脚本，此时 chrome 会忽略 proxy-bypass-list
+	if len(in.ProxyRules) > 0 {
+		opts = append(opts, chromedp.Flag("proxy-pac-url", pacURL(in)))
+	} else if in.Proxy != "" {
 		opts = append(opts, chromedp.ProxyServer(in.Proxy))
+		if len(in.ProxyBypass) > 0 {
+			opts = append(opts, chromedp.Flag("proxy-bypass-list", strings.Join(in.ProxyBypass, ";")))
+		}
 	}
 
 	if models.Debug {
@@ -50,66 +58,151 @@ func ChromeActions(in models.ChromeActionInput, logf func(string, ...interface{}
 		opts = append(opts, chromedp.Flag("auto-open-devtools-for-tabs", true))
 	}
 
-	allocCtx, bcancel := chromedp.NewExecAllocator(context.Background(), opts...)
-	defer func() {
+	return opts
+}
+
+// newChromeContext 创建本次渲染使用的 chromedp 上下文
+// 配置了浏览器池时从池中获取隐身上下文，否则启动一个新的浏览器
+// 隐身上下文不支持 PAC 脚本，有 proxy_rules 时同样单独启动浏览器
+func newChromeContext(parent context.Context, in models.ChromeActionInput, logf func(string, ...interface{})) (context.Context, context.CancelFunc, error) {
+	if Pool != nil && len(in.ProxyRules) == 0 {
+		var opts []chromedp.CreateBrowserContextOption
+		if in.Proxy != "" {
+			opts = append(opts, func(p *target.CreateBrowserContextParams) *target.CreateBrowserContextParams {
+				p = p.WithProxyServer(in.Proxy)
+				if len(in.ProxyBypass) > 0 {
+					p = p.WithProxyBypassList(strings.Join(in.ProxyBypass, ";"))
+				}
+				return p
+			})
+		}
+		ctx, release, err := Pool.Acquire(parent, opts...)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// 池中上下文派生自浏览器，需要单独跟随 parent 取消
+		ctx, cancel := context.WithCancel(ctx)
+		go func() {
+			select {
+			case <-parent.Done():
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		return ctx, func() {
+			cancel()
+			release()
+		}, nil
+	}
+
+	allocCtx, bcancel := chromedp.NewExecAllocator(parent, ExecAllocatorOptions(in)...)
+	ctx, cancel := chromedp.NewContext(allocCtx, chromedp.WithLogf(logf))
+	return ctx, func() {
+		cancel()
 		bcancel()
 		b := chromedp.FromContext(allocCtx).Browser
 		if b != nil && b.Process() != nil {
 			b.Process().Signal(os.Kill)
 		}
-	}()
+	}, nil
+}
 
-	ctx, cancel := chromedp.NewContext(allocCtx, chromedp.WithLogf(logf))
-	defer cancel()
-	ctx, cancel = context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+// ChromeActions 完成chrome的headless操作
+func ChromeActions(in models.ChromeActionInput, logf func(string, ...interface{}), timeout int, preActions []chromedp.Action, actions ...chromedp.Action) error {
+	_, err := ChromeActionsContext(context.Background(), in, logf, timeout, preActions, actions...)
+	return err
+}
+
+// ChromeActionsContext 同 ChromeActions，parent 取消时中止渲染，同时返回渲染过程中收集的信息
+func ChromeActionsContext(parent context.Context, in models.ChromeActionInput, logf func(string, ...interface{}), timeout int, preActions []chromedp.Action, actions ...chromedp.Action) (*models.ChromeActionOutput, error) {
+	out := &models.ChromeActionOutput{}
+
+	// 设备模拟
+	emulate, err := emulateActions(in)
+	if err != nil {
+		return out, err
+	}
+
+	// 自定义脚本
+	beforeScript, afterScript, err := scriptActions(in, out)
+	if err != nil {
+		return out, err
+	}
+
+	if err = validateSteps(in.Steps); err != nil {
+		return out, err
+	}
+
+	// 页面就绪条件
+	strategy, err := waitStrategy(in)
+	if err != nil {
+		return out, err
+	}
+
+	// 请求头和 cookie
+	requests, err := requestActions(in)
+	if err != nil {
+		return out, err
+	}
+
+	// 代理，认证代理的账号通过拦截请求提供
+	proxyAuth, err := resolveProxies(&in)
+	if err != nil {
+		return out, err
+	}
+
+	// 拦截请求
+	intercept, err := newInterceptor(in, proxyAuth)
+	if err != nil {
+		return out, err
+	}
+
+	// set user-agent
+	if in.UserAgent == "" {
+		in.UserAgent = models.DefaultUserAgent
+	}
+
+	ctx, cancel, err := newChromeContext(parent, in, logf)
+	if err != nil {
+		return out, err
+	}
 	defer cancel()
 
-	realActions := []chromedp.Action{
-		chromedp.ActionFunc(func(cxt context.Context) error {
-			// 等待完成，要么是body出来了，要么是资源加载完成
-			ch := make(chan error, 1)
-			go func(eCh chan error) {
-				err := chromedp.Navigate(in.URL).Do(cxt)
-				if err != nil {
-					eCh <- err
-				}
-				var htmlDom string
-				err = chromedp.WaitReady("body", chromedp.ByQuery).Do(cxt)
-				if err == nil {
-					if err2 := chromedp.OuterHTML("html", &htmlDom).Do(cxt); err != nil {
-						log.Println("[DEBUG] fetch html failed:", err2)
-					}
-				}
-				// 20211219发现如果存在JS前端框架 (如vue, react...) 执行等待读取.
-				html2Low := strings.ToLower(htmlDom)
-				if strings.Contains(html2Low, "javascript") || strings.Contains(html2Low, "</script>'") {
-					err2 := chromedp.WaitVisible("div", chromedp.ByQuery).Do(cxt)
-					if err2 = chromedp.OuterHTML("html", &htmlDom).Do(cxt); err2 != nil {
-						// extra error, doesnt affect anything else
-						log.Println("[DEBUG] fetch html failed:", err2)
-					}
-				}
+	// 记录跳转链和最终响应
+	nav := &navigationRecorder{}
+	chromedp.ListenTarget(ctx, nav.OnEvent)
+
+	// 收集控制台消息
+	var console *consoleRecorder
+	if in.CollectConsole {
+		console = newConsoleRecorder()
+		chromedp.ListenTarget(ctx, console.OnEvent)
+	}
 
-				eCh <- err
+	// 记录网络请求
+	var recorder *har.Recorder
+	if in.HAR {
+		recorder = har.NewRecorder(in.HARBodySize)
+		chromedp.ListenTarget(ctx, recorder.OnEvent)
+		actions = append(actions, recorder.FetchBodies())
+	}
 
-				// 将休眠移至当前位置，兼容title & location的获取
-				err = chromedp.Sleep(time.Duration(in.Sleep) * time.Second).Do(ctx)
-				if err != nil {
-					eCh <- err
-				}
-				eCh <- errors.New("finished")
-			}(ch)
+	if intercept != nil {
+		requests = append(requests, intercept.listen(ctx))
+	}
 
-			select {
-			case <-time.After(time.Duration(timeout) * time.Second):
-			case err := <-ch:
-				if err != nil {
-					return err
-				}
-			}
+	var setup []chromedp.Action
+	setup = append(setup, emulate...)
+	setup = append(setup, requests...)
+	setup = append(setup, beforeScript...)
+	preActions = append(setup, preActions...)
+
+	ctx, cancel = context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+	defer cancel()
 
-			return nil
-		}),
+	realActions := []chromedp.Action{
+		navigateAction(in, strategy),
 	}
 
 	// 用于RenderDOM中的actions执行
@@ -118,15 +211,29 @@ func ChromeActions(in models.ChromeActionInput, logf func(string, ...interface{}
 	}
 
 	realActions = append(preActions, realActions...)
+	if len(in.Steps) > 0 {
+		realActions = append(realActions, stepsAction(in.Steps, out))
+	}
+	realActions = append(realActions, afterScript...)
+	if in.CollectStorage {
+		realActions = append(realActions, storageAction(out))
+	}
 	realActions = append(realActions, actions...)
 
 	// run task list
 	err = chromedp.Run(ctx, realActions...)
 
-	// 以 finished 作为当前任务结尾
-	if err != nil && err.Error() == "finished" {
-		return nil
+	out.Redirects, out.Response = nav.result()
+	if console != nil {
+		out.Console = console.result()
+	}
+	if intercept != nil {
+		out.Blocked = intercept.blockStats()
+	}
+	// 出错时也返回已经记录的请求，便于排查
+	if recorder != nil {
+		out.HAR = recorder.HAR()
 	}
 
-	return err
+	return out, err
 }
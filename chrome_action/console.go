@@ -0,0 +1,167 @@
+package chrome_action
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/LubyRuffy/chrome_proxy/models"
+	cdplog "github.com/chromedp/cdproto/log"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/runtime"
+	"strings"
+	"sync"
+)
+
+// maxConsoleMessages 最多收集的控制台消息数，避免死循环打印的页面撑爆结果
+const maxConsoleMessages = 1000
+
+// consoleRecorder 收集控制台输出、未捕获异常和资源加载失败
+type consoleRecorder struct {
+	mu       sync.Mutex
+	urls     map[network.RequestID]string
+	messages []models.ConsoleMessage
+}
+
+func newConsoleRecorder() *consoleRecorder {
+	return &consoleRecorder{
+		urls: make(map[network.RequestID]string),
+	}
+}
+
+// OnEvent 处理 chromedp.ListenTarget 的事件
+func (r *consoleRecorder) OnEvent(ev interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch ev := ev.(type) {
+	case *runtime.EventConsoleAPICalled:
+		var args []string
+		for _, arg := range ev.Args {
+			args = append(args, formatRemoteObject(arg))
+		}
+		msg := models.ConsoleMessage{
+			Source: "console",
+			Level:  consoleLevel(ev.Type),
+			Text:   strings.Join(args, " "),
+		}
+		setLocation(&msg, ev.StackTrace)
+		r.add(msg)
+	case *runtime.EventExceptionThrown:
+		d := ev.ExceptionDetails
+		msg := models.ConsoleMessage{
+			Source: "exception",
+			Level:  "error",
+			Text:   d.Text,
+			URL:    d.URL,
+			Line:   d.LineNumber + 1,
+			Column: d.ColumnNumber + 1,
+		}
+		// Text 通常只是 "Uncaught"，具体错误在异常对象中
+		if d.Exception != nil && d.Exception.Description != "" {
+			msg.Text = d.Text + " " + d.Exception.Description
+		}
+		if msg.URL == "" {
+			setLocation(&msg, d.StackTrace)
+		}
+		r.add(msg)
+	case *cdplog.EventEntryAdded:
+		// 网络相关的错误通过 Network 事件记录
+		if ev.Entry.Source == cdplog.SourceNetwork {
+			return
+		}
+		msg := models.ConsoleMessage{
+			Source: string(ev.Entry.Source),
+			Level:  logLevel(ev.Entry.Level),
+			Text:   ev.Entry.Text,
+			URL:    ev.Entry.URL,
+			Line:   ev.Entry.LineNumber,
+		}
+		if msg.URL == "" {
+			setLocation(&msg, ev.Entry.StackTrace)
+		}
+		r.add(msg)
+	case *network.EventRequestWillBeSent:
+		r.urls[ev.RequestID] = ev.Request.URL
+	case *network.EventResponseReceived:
+		if ev.Response.Status >= 400 {
+			r.add(models.ConsoleMessage{
+				Source: "network",
+				Level:  "error",
+				Text:   fmt.Sprintf("Failed to load resource: the server responded with a status of %d (%s)", ev.Response.Status, ev.Response.StatusText),
+				URL:    ev.Response.URL,
+			})
+		}
+	case *network.EventLoadingFailed:
+		// 页面跳转等原因取消的请求，以及 block_resource_types 等主动拦截的请求不算失败
+		if ev.Canceled || ev.ErrorText == "net::ERR_BLOCKED_BY_CLIENT" {
+			return
+		}
+		r.add(models.ConsoleMessage{
+			Source: "network",
+			Level:  "error",
+			Text:   "Failed to load resource: " + ev.ErrorText,
+			URL:    r.urls[ev.RequestID],
+		})
+	}
+}
+
+func (r *consoleRecorder) add(msg models.ConsoleMessage) {
+	if len(r.messages) < maxConsoleMessages {
+		r.messages = append(r.messages, msg)
+	}
+}
+
+func (r *consoleRecorder) result() []models.ConsoleMessage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.messages
+}
+
+// setLocation 使用调用栈的第一帧作为消息位置
+func setLocation(msg *models.ConsoleMessage, st *runtime.StackTrace) {
+	if st == nil || len(st.CallFrames) == 0 {
+		return
+	}
+	f := st.CallFrames[0]
+	msg.URL = f.URL
+	msg.Line = f.LineNumber + 1
+	msg.Column = f.ColumnNumber + 1
+}
+
+// formatRemoteObject 将 console 的参数转换为字符串，字符串原样输出，其他值输出 json 或描述
+func formatRemoteObject(o *runtime.RemoteObject) string {
+	if len(o.Value) > 0 {
+		var s string
+		if o.Type == runtime.TypeString && json.Unmarshal(o.Value, &s) == nil {
+			return s
+		}
+		return string(o.Value)
+	}
+	if o.UnserializableValue != "" {
+		return string(o.UnserializableValue)
+	}
+	if o.Description != "" {
+		return o.Description
+	}
+	return string(o.Type)
+}
+
+func consoleLevel(t runtime.APIType) string {
+	switch t {
+	case runtime.APITypeError, runtime.APITypeAssert:
+		return "error"
+	case runtime.APITypeWarning:
+		return "warning"
+	case runtime.APITypeInfo:
+		return "info"
+	case runtime.APITypeDebug:
+		return "debug"
+	}
+	return "log"
+}
+
+func logLevel(l cdplog.Level) string {
+	if l == cdplog.LevelVerbose {
+		return "debug"
+	}
+	return string(l)
+}
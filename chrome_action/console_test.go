@@ -0,0 +1,91 @@
+package chrome_action
+
+import (
+	"github.com/LubyRuffy/chrome_proxy/models"
+	cdplog "github.com/chromedp/cdproto/log"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFormatRemoteObject(t *testing.T) {
+	tests := []struct {
+		name string
+		obj  *runtime.RemoteObject
+		want string
+	}{
+		{
+			name: "测试字符串",
+			obj:  &runtime.RemoteObject{Type: runtime.TypeString, Value: []byte(`"hello"`)},
+			want: "hello",
+		},
+		{
+			name: "测试数字",
+			obj:  &runtime.RemoteObject{Type: runtime.TypeNumber, Value: []byte(`1.5`)},
+			want: "1.5",
+		},
+		{
+			name: "测试NaN",
+			obj:  &runtime.RemoteObject{Type: runtime.TypeNumber, UnserializableValue: "NaN"},
+			want: "NaN",
+		},
+		{
+			name: "测试对象",
+			obj:  &runtime.RemoteObject{Type: runtime.TypeObject, Description: "Object"},
+			want: "Object",
+		},
+		{
+			name: "测试undefined",
+			obj:  &runtime.RemoteObject{Type: runtime.TypeUndefined},
+			want: "undefined",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, formatRemoteObject(tt.obj))
+		})
+	}
+}
+
+func TestConsoleRecorder(t *testing.T) {
+	r := newConsoleRecorder()
+	events := []interface{}{
+		&runtime.EventConsoleAPICalled{
+			Type: runtime.APITypeWarning,
+			Args: []*runtime.RemoteObject{
+				{Type: runtime.TypeString, Value: []byte(`"count:"`)},
+				{Type: runtime.TypeNumber, Value: []byte(`3`)},
+			},
+			StackTrace: &runtime.StackTrace{CallFrames: []*runtime.CallFrame{
+				{URL: "https://a.com/app.js", LineNumber: 9, ColumnNumber: 4},
+			}},
+		},
+		&runtime.EventExceptionThrown{ExceptionDetails: &runtime.ExceptionDetails{
+			Text:         "Uncaught",
+			URL:          "https://a.com/app.js",
+			LineNumber:   19,
+			ColumnNumber: 0,
+			Exception:    &runtime.RemoteObject{Description: "TypeError: x is undefined"},
+		}},
+		&cdplog.EventEntryAdded{Entry: &cdplog.Entry{Source: cdplog.SourceNetwork, Level: cdplog.LevelError, Text: "Failed to load resource"}},
+		&cdplog.EventEntryAdded{Entry: &cdplog.Entry{Source: cdplog.SourceSecurity, Level: cdplog.LevelVerbose, Text: "mixed content", URL: "https://a.com/"}},
+		&network.EventRequestWillBeSent{RequestID: "1", Request: &network.Request{URL: "https://b.com/x.js"}},
+		&network.EventLoadingFailed{RequestID: "1", ErrorText: "net::ERR_NAME_NOT_RESOLVED"},
+		&network.EventRequestWillBeSent{RequestID: "2", Request: &network.Request{URL: "https://a.com/old"}},
+		&network.EventLoadingFailed{RequestID: "2", ErrorText: "net::ERR_ABORTED", Canceled: true},
+		&network.EventLoadingFailed{RequestID: "4", ErrorText: "net::ERR_BLOCKED_BY_CLIENT"},
+		&network.EventResponseReceived{RequestID: "3", Response: &network.Response{URL: "https://a.com/404.png", Status: 404, StatusText: "Not Found"}},
+	}
+	for _, ev := range events {
+		r.OnEvent(ev)
+	}
+
+	assert.Equal(t, []models.ConsoleMessage{
+		{Source: "console", Level: "warning", Text: "count: 3", URL: "https://a.com/app.js", Line: 10, Column: 5},
+		{Source: "exception", Level: "error", Text: "Uncaught TypeError: x is undefined", URL: "https://a.com/app.js", Line: 20, Column: 1},
+		{Source: "security", Level: "debug", Text: "mixed content", URL: "https://a.com/"},
+		{Source: "network", Level: "error", Text: "Failed to load resource: net::ERR_NAME_NOT_RESOLVED", URL: "https://b.com/x.js"},
+		{Source: "network", Level: "error", Text: "Failed to load resource: the server responded with a status of 404 (Not Found)", URL: "https://a.com/404.png"},
+	}, r.result())
+}
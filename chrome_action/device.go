@@ -0,0 +1,90 @@
+package chrome_action
+
+import (
+	"fmt"
+	"github.com/LubyRuffy/chrome_proxy/models"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+	"github.com/chromedp/chromedp/device"
+	"strings"
+)
+
+// deviceAliases 只写设备系列名时使用的默认设备
+var deviceAliases = map[string]string{
+	"iphone":  "iphone13",
+	"ipad":    "ipadpro11",
+	"pixel":   "pixel5",
+	"galaxy":  "galaxys9+",
+	"android": "pixel5",
+}
+
+// normalizeDeviceName 去掉空格、括号等字符并转为小写，"iPhone 13 Pro" => "iphone13pro"
+func normalizeDeviceName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '_', '(', ')':
+			return -1
+		}
+		return r
+	}, strings.ToLower(name))
+}
+
+// LookupDevice 按名称查找 chromedp 内置的设备，名称不区分大小写和空格
+func LookupDevice(name string) (device.Info, bool) {
+	name = normalizeDeviceName(name)
+	if alias, ok := deviceAliases[name]; ok {
+		name = alias
+	}
+
+	for d := device.Reset + 1; d <= device.MotoG4landscape; d++ {
+		if normalizeDeviceName(d.String()) == name {
+			return d.Device(), true
+		}
+	}
+	return device.Info{}, false
+}
+
+// emulateActions 生成视口、缩放比例以及移动设备模拟的动作
+// 调用方明确指定的 UA 会覆盖设备预设的 UA
+func emulateActions(in models.ChromeActionInput) ([]chromedp.Action, error) {
+	userAgent := in.UserAgent
+	var actions []chromedp.Action
+
+	if in.Device != "" {
+		info, ok := LookupDevice(in.Device)
+		if !ok {
+			return nil, fmt.Errorf("unknown device: %s", in.Device)
+		}
+		actions = append(actions, chromedp.Emulate(info))
+		if userAgent != "" {
+			actions = append(actions, emulation.SetUserAgentOverride(userAgent))
+		}
+		return actions, nil
+	}
+
+	// 浏览器池中的浏览器是共享的，UA 和窗口大小只能按页面设置
+	if Pool != nil {
+		if userAgent == "" {
+			userAgent = models.DefaultUserAgent
+		}
+		actions = append(actions, emulation.SetUserAgentOverride(userAgent))
+	} else if in.Width == 0 && in.Height == 0 && in.DeviceScaleFactor == 0 && !in.Mobile {
+		return nil, nil
+	}
+
+	width, height, scale := int64(in.Width), int64(in.Height), in.DeviceScaleFactor
+	if width <= 0 {
+		width = 1024
+	}
+	if height <= 0 {
+		height = 768
+	}
+	if scale <= 0 {
+		scale = 1
+	}
+	actions = append(actions,
+		emulation.SetDeviceMetricsOverride(width, height, scale, in.Mobile),
+		emulation.SetTouchEmulationEnabled(in.Mobile),
+	)
+	return actions, nil
+}
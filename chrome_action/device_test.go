@@ -0,0 +1,99 @@
+package chrome_action
+
+import (
+	"github.com/LubyRuffy/chrome_proxy/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLookupDevice(t *testing.T) {
+	tests := []struct {
+		name     string
+		device   string
+		wantName string
+		wantOk   bool
+	}{
+		{
+			name:     "测试完整设备名",
+			device:   "iPhone 13 Pro",
+			wantName: "iPhone 13 Pro",
+			wantOk:   true,
+		},
+		{
+			name:     "测试大小写和空格",
+			device:   "pixel5",
+			wantName: "Pixel 5",
+			wantOk:   true,
+		},
+		{
+			name:     "测试设备系列别名",
+			device:   "iPad",
+			wantName: "iPad Pro 11",
+			wantOk:   true,
+		},
+		{
+			name:     "测试带加号的设备别名",
+			device:   "galaxy",
+			wantName: "Galaxy S9+",
+			wantOk:   true,
+		},
+		{
+			name:   "测试未知设备",
+			device: "Nokia 3310",
+			wantOk: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, ok := LookupDevice(tt.device)
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.wantName, info.Name)
+		})
+	}
+}
+
+func TestEmulateActions(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      models.ChromeActionInput
+		wantLen int
+		wantErr bool
+	}{
+		{
+			name:    "测试默认不模拟",
+			in:      models.ChromeActionInput{},
+			wantLen: 0,
+		},
+		{
+			name:    "测试自定义视口",
+			in:      models.ChromeActionInput{Width: 375, Height: 812, DeviceScaleFactor: 3, Mobile: true},
+			wantLen: 2,
+		},
+		{
+			name:    "测试设备预设",
+			in:      models.ChromeActionInput{Device: "iPhone X"},
+			wantLen: 1,
+		},
+		{
+			name:    "测试设备预设并指定UA",
+			in:      models.ChromeActionInput{Device: "iPhone X", UserAgent: "test"},
+			wantLen: 2,
+		},
+		{
+			name:    "测试未知设备",
+			in:      models.ChromeActionInput{Device: "unknown"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := emulateActions(tt.in)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Len(t, got, tt.wantLen)
+		})
+	}
+}
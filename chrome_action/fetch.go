@@ -0,0 +1,144 @@
+package chrome_action
+
+import (
+	"context"
+	"github.com/LubyRuffy/chrome_proxy/models"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"log"
+	"strings"
+	"sync"
+)
+
+// maxAuthAttempts 同一来源最多提供账号的次数，账号错误时避免无限重试
+const maxAuthAttempts = 3
+
+// interceptor 通过 Fetch 域拦截请求，处理认证、请求拦截和改写
+type interceptor struct {
+	ctx       context.Context // chromedp 上下文，用于在事件回调中发送命令
+	httpAuth  *models.Credentials
+	proxyAuth map[string]*models.Credentials // 代理的 host:port 对应的账号
+	block     *blockRules
+	rules     []*fetchRule
+
+	mu       sync.Mutex
+	attempts map[string]int
+	blocked  *models.BlockStats
+}
+
+// newInterceptor 检查拦截参数，不需要拦截请求时返回 nil
+func newInterceptor(in models.ChromeActionInput, proxyAuth map[string]*models.Credentials) (*interceptor, error) {
+	block, err := newBlockRules(in)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := compileFetchRules(in.FetchRules)
+	if err != nil {
+		return nil, err
+	}
+	if in.HTTPAuth == nil && proxyAuth == nil && block == nil && len(rules) == 0 {
+		return nil, nil
+	}
+
+	i := &interceptor{
+		httpAuth:  in.HTTPAuth,
+		proxyAuth: proxyAuth,
+		block:     block,
+		rules:     rules,
+		attempts:  make(map[string]int),
+	}
+	if block != nil {
+		i.blocked = &models.BlockStats{ByType: make(map[string]int)}
+	}
+	return i, nil
+}
+
+// listen 监听 ctx 中页面的事件，并返回开启拦截的动作，需要在导航前执行
+func (i *interceptor) listen(ctx context.Context) chromedp.Action {
+	i.ctx = ctx
+	chromedp.ListenTarget(ctx, i.OnEvent)
+	return fetch.Enable().WithHandleAuthRequests(true)
+}
+
+// OnEvent 处理 chromedp.ListenTarget 的事件
+// 监听函数中不能阻塞，命令需要在单独的 goroutine 中发送
+func (i *interceptor) OnEvent(ev interface{}) {
+	switch ev := ev.(type) {
+	case *fetch.EventRequestPaused:
+		if i.block != nil && i.block.match(ev.Request.URL, ev.ResourceType) {
+			i.countBlocked(ev.ResourceType)
+			i.do(fetch.FailRequest(ev.RequestID, network.ErrorReasonBlockedByClient))
+			return
+		}
+		if rule := matchFetchRule(i.rules, ev.Request.URL, ev.ResourceType); rule != nil {
+			i.do(rule.action(ev))
+			return
+		}
+		i.do(fetch.ContinueRequest(ev.RequestID))
+	case *fetch.EventAuthRequired:
+		i.do(fetch.ContinueWithAuth(ev.RequestID, i.authChallengeResponse(ev.AuthChallenge)))
+	}
+}
+
+func (i *interceptor) countBlocked(t network.ResourceType) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.blocked.Total++
+	i.blocked.ByType[strings.ToLower(string(t))]++
+}
+
+// blockStats 返回拦截统计，没有配置拦截时返回 nil
+func (i *interceptor) blockStats() *models.BlockStats {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.blocked == nil {
+		return nil
+	}
+	stats := &models.BlockStats{Total: i.blocked.Total, ByType: make(map[string]int, len(i.blocked.ByType))}
+	for k, v := range i.blocked.ByType {
+		stats.ByType[k] = v
+	}
+	return stats
+}
+
+// authChallengeResponse 根据认证来源返回对应的账号，没有账号或者多次失败时取消认证
+func (i *interceptor) authChallengeResponse(challenge *fetch.AuthChallenge) *fetch.AuthChallengeResponse {
+	cancel := &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseCancelAuth}
+
+	cred := i.httpAuth
+	if challenge.Source == fetch.AuthChallengeSourceProxy {
+		cred = i.proxyAuth[proxyHost(challenge.Origin)]
+	}
+	if cred == nil {
+		return cancel
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	key := string(challenge.Source) + " " + challenge.Origin
+	if i.attempts[key] >= maxAuthAttempts {
+		return cancel
+	}
+	i.attempts[key]++
+
+	return &fetch.AuthChallengeResponse{
+		Response: fetch.AuthChallengeResponseResponseProvideCredentials,
+		Username: cred.Username,
+		Password: cred.Password,
+	}
+}
+
+func (i *interceptor) do(action chromedp.Action) {
+	go func() {
+		c := chromedp.FromContext(i.ctx)
+		if c == nil || c.Target == nil {
+			return
+		}
+		err := action.Do(cdp.WithExecutor(i.ctx, c.Target))
+		if err != nil && i.ctx.Err() == nil {
+			log.Println("[DEBUG] fetch interception failed:", err)
+		}
+	}()
+}
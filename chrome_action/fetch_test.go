@@ -0,0 +1,69 @@
+package chrome_action
+
+import (
+	"github.com/LubyRuffy/chrome_proxy/models"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestInterceptor_authChallengeResponse(t *testing.T) {
+	i, err := newInterceptor(models.ChromeActionInput{}, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, i)
+
+	i, err = newInterceptor(models.ChromeActionInput{
+		HTTPAuth: &models.Credentials{Username: "admin", Password: "123456"},
+	}, nil)
+	assert.Nil(t, err)
+	server := &fetch.AuthChallenge{Source: fetch.AuthChallengeSourceServer, Origin: "https://example.com", Scheme: "basic"}
+	provide := &fetch.AuthChallengeResponse{
+		Response: fetch.AuthChallengeResponseResponseProvideCredentials,
+		Username: "admin",
+		Password: "123456",
+	}
+	cancel := &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseCancelAuth}
+
+	// 账号错误时最多重试 maxAuthAttempts 次
+	for n := 0; n < maxAuthAttempts; n++ {
+		assert.Equal(t, provide, i.authChallengeResponse(server))
+	}
+	assert.Equal(t, cancel, i.authChallengeResponse(server))
+
+	// 没有代理账号
+	proxy := &fetch.AuthChallenge{Source: fetch.AuthChallengeSourceProxy, Origin: "http://127.0.0.1:8080"}
+	assert.Equal(t, cancel, i.authChallengeResponse(proxy))
+
+	// 代理账号与网站账号分开使用
+	i, err = newInterceptor(models.ChromeActionInput{}, map[string]*models.Credentials{
+		"127.0.0.1:8080": {Username: "user", Password: "pass"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, &fetch.AuthChallengeResponse{
+		Response: fetch.AuthChallengeResponseResponseProvideCredentials,
+		Username: "user",
+		Password: "pass",
+	}, i.authChallengeResponse(proxy))
+	assert.Equal(t, cancel, i.authChallengeResponse(server))
+	// 其他代理没有账号
+	assert.Equal(t, cancel, i.authChallengeResponse(&fetch.AuthChallenge{Source: fetch.AuthChallengeSourceProxy, Origin: "http://10.0.0.1:3128"}))
+}
+
+func TestInterceptor_blockStats(t *testing.T) {
+	i, err := newInterceptor(models.ChromeActionInput{HTTPAuth: &models.Credentials{}}, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, i.blockStats())
+
+	i, err = newInterceptor(models.ChromeActionInput{BlockResourceTypes: []string{"image", "Font"}}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, &models.BlockStats{ByType: map[string]int{}}, i.blockStats())
+
+	i.countBlocked(network.ResourceTypeImage)
+	i.countBlocked(network.ResourceTypeImage)
+	i.countBlocked(network.ResourceTypeFont)
+	assert.Equal(t, &models.BlockStats{Total: 3, ByType: map[string]int{"image": 2, "font": 1}}, i.blockStats())
+
+	_, err = newInterceptor(models.ChromeActionInput{BlockResourceTypes: []string{"video"}}, nil)
+	assert.Error(t, err)
+}
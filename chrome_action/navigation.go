@@ -0,0 +1,121 @@
+package chrome_action
+
+import (
+	"fmt"
+	"github.com/LubyRuffy/chrome_proxy/models"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"sync"
+)
+
+// navigationRecorder 记录主页面的跳转链和最终响应
+type navigationRecorder struct {
+	mu        sync.Mutex
+	frameID   cdp.FrameID
+	reason    page.ClientNavigationReason // 页面主动发起的下一次跳转的原因
+	chain     []models.Navigation
+	requestID network.RequestID
+	response  *network.Response
+}
+
+// OnEvent 处理 chromedp.ListenTarget 的事件
+func (r *navigationRecorder) OnEvent(ev interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch ev := ev.(type) {
+	case *page.EventFrameRequestedNavigation:
+		if ev.FrameID == r.frameID {
+			r.reason = ev.Reason
+		}
+	case *network.EventRequestWillBeSent:
+		if ev.Type != network.ResourceTypeDocument {
+			return
+		}
+		// 第一个文档请求来自 Navigate，所在的 frame 即为主页面
+		if r.frameID == "" {
+			r.frameID = ev.FrameID
+		} else if ev.FrameID != r.frameID {
+			return
+		}
+
+		typ := models.NavigationInitial
+		if len(r.chain) > 0 {
+			last := &r.chain[len(r.chain)-1]
+			switch {
+			case ev.RedirectResponse != nil && ev.RequestID == r.requestID:
+				last.Status = ev.RedirectResponse.Status
+				typ = models.NavigationHTTP
+			case r.reason != "":
+				typ = navigationType(r.reason)
+			default:
+				typ = models.NavigationOther
+			}
+		}
+		r.reason = ""
+		r.chain = append(r.chain, models.Navigation{URL: ev.Request.URL + ev.Request.URLFragment, Type: typ})
+		r.requestID = ev.RequestID
+		r.response = nil
+	case *network.EventResponseReceived:
+		if ev.RequestID == r.requestID && ev.Type == network.ResourceTypeDocument && len(r.chain) > 0 {
+			r.response = ev.Response
+			r.chain[len(r.chain)-1].Status = ev.Response.Status
+		}
+	}
+}
+
+// result 返回跳转链和最终响应
+func (r *navigationRecorder) result() ([]models.Navigation, *models.ResponseInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.response == nil {
+		return r.chain, nil
+	}
+	return r.chain, responseInfo(r.response)
+}
+
+func navigationType(reason page.ClientNavigationReason) string {
+	switch reason {
+	case page.ClientNavigationReasonHTTPHeaderRefresh:
+		return models.NavigationRefresh
+	case page.ClientNavigationReasonMetaTagRefresh:
+		return models.NavigationMeta
+	case page.ClientNavigationReasonScriptInitiated:
+		return models.NavigationJS
+	}
+	return models.NavigationOther
+}
+
+// responseInfo 转换 chrome 的响应信息
+func responseInfo(resp *network.Response) *models.ResponseInfo {
+	info := &models.ResponseInfo{
+		Status:     resp.Status,
+		StatusText: resp.StatusText,
+		Headers:    make(map[string]string, len(resp.Headers)),
+		Protocol:   resp.Protocol,
+		RemoteIP:   resp.RemoteIPAddress,
+		RemotePort: resp.RemotePort,
+	}
+	for k, v := range resp.Headers {
+		info.Headers[k] = fmt.Sprint(v)
+	}
+
+	if d := resp.SecurityDetails; d != nil {
+		info.TLS = &models.TLSInfo{
+			Protocol: d.Protocol,
+			Cipher:   d.Cipher,
+			Subject:  d.SubjectName,
+			Issuer:   d.Issuer,
+			SANs:     d.SanList,
+		}
+		if d.ValidFrom != nil {
+			info.TLS.ValidFrom = d.ValidFrom.Time().UTC()
+		}
+		if d.ValidTo != nil {
+			info.TLS.ValidTo = d.ValidTo.Time().UTC()
+		}
+	}
+	return info
+}
@@ -0,0 +1,94 @@
+package chrome_action
+
+import (
+	"github.com/LubyRuffy/chrome_proxy/models"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func documentRequest(id network.RequestID, frameID cdp.FrameID, url string, redirect *network.Response) *network.EventRequestWillBeSent {
+	return &network.EventRequestWillBeSent{
+		RequestID:        id,
+		FrameID:          frameID,
+		Type:             network.ResourceTypeDocument,
+		Request:          &network.Request{URL: url},
+		RedirectResponse: redirect,
+	}
+}
+
+func documentResponse(id network.RequestID, resp *network.Response) *network.EventResponseReceived {
+	return &network.EventResponseReceived{
+		RequestID: id,
+		Type:      network.ResourceTypeDocument,
+		Response:  resp,
+	}
+}
+
+func TestNavigationRecorder(t *testing.T) {
+	validFrom := cdp.TimeSinceEpoch(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC))
+	validTo := cdp.TimeSinceEpoch(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	r := &navigationRecorder{}
+	events := []interface{}{
+		documentRequest("1", "main", "http://a.com/", nil),
+		documentRequest("1", "main", "https://a.com/", &network.Response{Status: 301}),
+		documentResponse("1", &network.Response{Status: 200}),
+		&page.EventFrameRequestedNavigation{FrameID: "main", Reason: page.ClientNavigationReasonMetaTagRefresh},
+		documentRequest("2", "main", "https://b.com/", nil),
+		documentResponse("2", &network.Response{Status: 200}),
+		// iframe 中的跳转不属于主页面
+		&page.EventFrameRequestedNavigation{FrameID: "iframe", Reason: page.ClientNavigationReasonScriptInitiated},
+		documentRequest("3", "iframe", "https://ads.com/", nil),
+		&page.EventFrameRequestedNavigation{FrameID: "main", Reason: page.ClientNavigationReasonScriptInitiated},
+		documentRequest("4", "main", "https://c.com/#x", nil),
+		documentResponse("4", &network.Response{
+			Status:          200,
+			StatusText:      "OK",
+			Headers:         network.Headers{"Server": "nginx"},
+			Protocol:        "h2",
+			RemoteIPAddress: "1.2.3.4",
+			RemotePort:      443,
+			SecurityDetails: &network.SecurityDetails{
+				Protocol:    "TLS 1.3",
+				Cipher:      "AES_128_GCM",
+				SubjectName: "c.com",
+				Issuer:      "R3",
+				SanList:     []string{"c.com", "www.c.com"},
+				ValidFrom:   &validFrom,
+				ValidTo:     &validTo,
+			},
+		}),
+	}
+	for _, ev := range events {
+		r.OnEvent(ev)
+	}
+
+	chain, resp := r.result()
+	assert.Equal(t, []models.Navigation{
+		{URL: "http://a.com/", Status: 301, Type: models.NavigationInitial},
+		{URL: "https://a.com/", Status: 200, Type: models.NavigationHTTP},
+		{URL: "https://b.com/", Status: 200, Type: models.NavigationMeta},
+		{URL: "https://c.com/#x", Status: 200, Type: models.NavigationJS},
+	}, chain)
+	assert.Equal(t, &models.ResponseInfo{
+		Status:     200,
+		StatusText: "OK",
+		Headers:    map[string]string{"Server": "nginx"},
+		Protocol:   "h2",
+		RemoteIP:   "1.2.3.4",
+		RemotePort: 443,
+		TLS: &models.TLSInfo{
+			Protocol:  "TLS 1.3",
+			Cipher:    "AES_128_GCM",
+			Subject:   "c.com",
+			Issuer:    "R3",
+			SANs:      []string{"c.com", "www.c.com"},
+			ValidFrom: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+			ValidTo:   time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}, resp)
+}
@@ -0,0 +1,174 @@
+package chrome_action
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/LubyRuffy/chrome_proxy/models"
+	"github.com/LubyRuffy/chrome_proxy/proxy_pool"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// ProxyPool 服务端代理池，为 nil 时不支持 proxy 为 pool 的请求
+var ProxyPool *proxy_pool.Pool
+
+// resolveProxy 解析请求的代理，返回 chrome 使用的代理地址（chrome 不支持地址中带账号）以及代理认证账号
+// proxy 为 pool 时从代理池中为 target 选择代理
+func resolveProxy(proxy string, target string) (string, *models.Credentials, error) {
+	if proxy == models.ProxyPool {
+		if ProxyPool == nil {
+			return "", nil, errors.New("proxy pool is not configured")
+		}
+		proxy = ProxyPool.Select(target)
+	}
+	if !strings.Contains(proxy, "@") {
+		return proxy, nil, nil
+	}
+
+	// 没有协议时按 http 代理解析
+	raw := proxy
+	hasScheme := strings.Contains(proxy, "://")
+	if !hasScheme {
+		raw = "http://" + proxy
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid proxy: %w", err)
+	}
+	if u.User == nil {
+		return proxy, nil, nil
+	}
+
+	// chrome 只会对 http 代理发起认证
+	if strings.HasPrefix(u.Scheme, "socks") {
+		return "", nil, errors.New("chrome does not support socks proxy authentication")
+	}
+
+	password, _ := u.User.Password()
+	auth := &models.Credentials{Username: u.User.Username(), Password: password}
+	if !hasScheme {
+		return u.Host, auth, nil
+	}
+	return u.Scheme + "://" + u.Host, auth, nil
+}
+
+// resolveProxies 解析 in 中的 proxy 和 proxy_rules，替换为不带账号的地址
+// 返回代理的 host:port 对应的认证账号，代理认证时按来源选择
+func resolveProxies(in *models.ChromeActionInput) (map[string]*models.Credentials, error) {
+	auth := make(map[string]*models.Credentials)
+	resolve := func(proxy string) (string, error) {
+		server, cred, err := resolveProxy(proxy, in.URL)
+		if err != nil {
+			return "", err
+		}
+		if cred != nil {
+			auth[proxyHost(server)] = cred
+		}
+		return server, nil
+	}
+
+	var err error
+	if in.Proxy, err = resolve(in.Proxy); err != nil {
+		return nil, err
+	}
+
+	// 复制一份，不修改调用方的规则
+	rules := make([]models.ProxyRule, len(in.ProxyRules))
+	for i, r := range in.ProxyRules {
+		if r.Host == "" {
+			return nil, fmt.Errorf("proxy rule %d: host is empty", i)
+		}
+		if r.Proxy == "" || strings.EqualFold(r.Proxy, models.ProxyDirect) {
+			r.Proxy = models.ProxyDirect
+		} else if r.Proxy, err = resolve(r.Proxy); err != nil {
+			return nil, fmt.Errorf("proxy rule %d: %w", i, err)
+		}
+		rules[i] = r
+	}
+	in.ProxyRules = rules
+
+	if len(auth) == 0 {
+		return nil, nil
+	}
+	return auth, nil
+}
+
+// proxyHost 返回代理地址的 host:port，与认证来源的 origin 对应
+func proxyHost(server string) string {
+	if !strings.Contains(server, "://") {
+		server = "http://" + server
+	}
+	u, err := url.Parse(server)
+	if err != nil {
+		return server
+	}
+	return u.Host
+}
+
+// pacURL 生成按主机选择代理的 PAC 脚本，以 data url 的形式传给 chrome
+func pacURL(in models.ChromeActionInput) string {
+	return "data:application/x-ns-proxy-autoconfig;base64," +
+		base64.StdEncoding.EncodeToString([]byte(pacScript(in.Proxy, in.ProxyBypass, in.ProxyRules)))
+}
+
+// pacScript 生成 PAC 脚本，直连的主机优先，然后按顺序匹配规则，最后使用默认代理
+func pacScript(proxy string, bypass []string, rules []models.ProxyRule) string {
+	var b strings.Builder
+	b.WriteString("function FindProxyForURL(url, host) {\n")
+	b.WriteString("  host = host.toLowerCase();\n")
+	for _, h := range bypass {
+		fmt.Fprintf(&b, "  if (%s) return \"DIRECT\";\n", pacCondition(h))
+	}
+	for _, r := range rules {
+		fmt.Fprintf(&b, "  if (%s) return %s;\n", pacCondition(r.Host), jsString(pacProxy(r.Proxy)))
+	}
+	fmt.Fprintf(&b, "  return %s;\n}\n", jsString(pacProxy(proxy)))
+	return b.String()
+}
+
+// pacCondition 将主机规则转换为 PAC 判断条件，格式与 chrome 的 proxy-bypass-list 保持一致
+func pacCondition(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	switch {
+	case host == "<local>":
+		return "isPlainHostName(host)"
+	case strings.Contains(host, "/"):
+		if _, _, err := net.ParseCIDR(host); err == nil {
+			// 与 chrome 一致，网段只匹配 ip 形式的主机，不解析域名
+			return "isInNetEx(host, " + jsString(host) + ")"
+		}
+	case strings.HasPrefix(host, "."):
+		host = "*" + host
+	}
+	return "shExpMatch(host, " + jsString(host) + ")"
+}
+
+// pacProxy 将代理地址转换为 PAC 的返回值
+func pacProxy(server string) string {
+	if server == "" || server == models.ProxyDirect {
+		return "DIRECT"
+	}
+	scheme, host := "http", server
+	if i := strings.Index(server, "://"); i >= 0 {
+		scheme, host = strings.ToLower(server[:i]), server[i+3:]
+	}
+	host = strings.TrimSuffix(host, "/")
+	switch scheme {
+	case "https":
+		return "HTTPS " + host
+	case "socks4":
+		return "SOCKS " + host
+	case "socks", "socks5":
+		return "SOCKS5 " + host
+	default:
+		return "PROXY " + host
+	}
+}
+
+func jsString(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
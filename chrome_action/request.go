@@ -0,0 +1,74 @@
+package chrome_action
+
+import (
+	"fmt"
+	"github.com/LubyRuffy/chrome_proxy/models"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"math"
+	"strings"
+	"time"
+)
+
+// requestActions 生成导航前设置请求头和 cookie 的动作
+func requestActions(in models.ChromeActionInput) ([]chromedp.Action, error) {
+	var actions []chromedp.Action
+
+	if len(in.Headers) > 0 {
+		headers := make(network.Headers, len(in.Headers))
+		for k, v := range in.Headers {
+			headers[k] = v
+		}
+		actions = append(actions, network.SetExtraHTTPHeaders(headers))
+	}
+
+	for _, c := range in.Cookies {
+		p, err := setCookieParams(c, in.URL)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, p)
+	}
+	return actions, nil
+}
+
+// setCookieParams 生成 Network.setCookie 的参数，没有指定域名时作用于 url
+func setCookieParams(c models.Cookie, url string) (*network.SetCookieParams, error) {
+	if c.Name == "" {
+		return nil, fmt.Errorf("cookie name is empty")
+	}
+
+	p := network.SetCookie(c.Name, c.Value).
+		WithSecure(c.Secure).
+		WithHTTPOnly(c.HTTPOnly)
+	if c.Domain == "" {
+		p = p.WithURL(url)
+	} else {
+		p = p.WithDomain(c.Domain)
+	}
+	if c.Path != "" {
+		p = p.WithPath(c.Path)
+	} else if c.Domain != "" {
+		// 指定域名时 chrome 不会根据 url 推断路径
+		p = p.WithPath("/")
+	}
+	if c.Expires > 0 {
+		sec, frac := math.Modf(c.Expires)
+		t := cdp.TimeSinceEpoch(time.Unix(int64(sec), int64(frac*float64(time.Second))))
+		p = p.WithExpires(&t)
+	}
+	if c.SameSite != "" {
+		switch strings.ToLower(c.SameSite) {
+		case "strict":
+			p = p.WithSameSite(network.CookieSameSiteStrict)
+		case "lax":
+			p = p.WithSameSite(network.CookieSameSiteLax)
+		case "none":
+			p = p.WithSameSite(network.CookieSameSiteNone)
+		default:
+			return nil, fmt.Errorf("unknown cookie same_site: %s", c.SameSite)
+		}
+	}
+	return p, nil
+}
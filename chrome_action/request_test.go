@@ -0,0 +1,56 @@
+package chrome_action
+
+import (
+	"github.com/LubyRuffy/chrome_proxy/models"
+	"github.com/chromedp/cdproto/network"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSetCookieParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		cookie  models.Cookie
+		want    *network.SetCookieParams
+		wantErr bool
+	}{
+		{
+			name:   "测试使用url",
+			cookie: models.Cookie{Name: "sid", Value: "1", HTTPOnly: true},
+			want:   network.SetCookie("sid", "1").WithSecure(false).WithHTTPOnly(true).WithURL("https://example.com/a/b"),
+		},
+		{
+			name:   "测试指定域名",
+			cookie: models.Cookie{Name: "sid", Value: "1", Domain: ".example.com", Secure: true, SameSite: "lax"},
+			want: network.SetCookie("sid", "1").WithSecure(true).WithHTTPOnly(false).
+				WithDomain(".example.com").WithPath("/").WithSameSite(network.CookieSameSiteLax),
+		},
+		{
+			name:    "测试缺少名称",
+			cookie:  models.Cookie{Value: "1"},
+			wantErr: true,
+		},
+		{
+			name:    "测试未知same_site",
+			cookie:  models.Cookie{Name: "sid", SameSite: "always"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := setCookieParams(tt.cookie, "https://example.com/a/b")
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSetCookieParams_Expires(t *testing.T) {
+	got, err := setCookieParams(models.Cookie{Name: "sid", Expires: 1700000000.5}, "https://example.com/")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1700000000500), got.Expires.Time().UnixMilli())
+}
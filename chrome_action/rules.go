@@ -0,0 +1,135 @@
+package chrome_action
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/LubyRuffy/chrome_proxy/models"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// fetchRule 编译后的请求改写规则
+type fetchRule struct {
+	models.FetchRule
+	pattern      *regexp.Regexp
+	resourceType network.ResourceType
+}
+
+// compileFetchRules 检查并编译请求改写规则
+func compileFetchRules(rules []models.FetchRule) ([]*fetchRule, error) {
+	var result []*fetchRule
+	for i, r := range rules {
+		if r.URLPattern == "" {
+			return nil, fmt.Errorf("fetch rule %d: url_pattern is empty", i)
+		}
+		re, err := compileURLPattern(r.URLPattern)
+		if err != nil {
+			return nil, fmt.Errorf("fetch rule %d: %w", i, err)
+		}
+		rule := &fetchRule{FetchRule: r, pattern: re}
+
+		if r.ResourceType != "" {
+			t, ok := resourceTypes[strings.ToLower(r.ResourceType)]
+			if !ok {
+				return nil, fmt.Errorf("fetch rule %d: unknown resource type: %s", i, r.ResourceType)
+			}
+			rule.resourceType = t
+		}
+
+		switch r.Action {
+		case models.FetchMock:
+			if rule.Status == 0 {
+				rule.Status = 200
+			}
+			if rule.Status < 100 || rule.Status > 599 {
+				return nil, fmt.Errorf("fetch rule %d: invalid status: %d", i, r.Status)
+			}
+			if r.BodyBase64 {
+				if _, err := base64.StdEncoding.DecodeString(r.Body); err != nil {
+					return nil, fmt.Errorf("fetch rule %d: invalid base64 body: %w", i, err)
+				}
+			}
+		case models.FetchHeaders:
+			if len(r.Headers) == 0 {
+				return nil, fmt.Errorf("fetch rule %d: headers is empty", i)
+			}
+		case models.FetchRedirect:
+			if r.RedirectURL == "" {
+				return nil, fmt.Errorf("fetch rule %d: redirect_url is empty", i)
+			}
+		default:
+			return nil, fmt.Errorf("fetch rule %d: unknown action: %s", i, r.Action)
+		}
+		result = append(result, rule)
+	}
+	return result, nil
+}
+
+// matchFetchRule 返回第一条匹配的规则
+func matchFetchRule(rules []*fetchRule, url string, t network.ResourceType) *fetchRule {
+	for _, r := range rules {
+		if r.resourceType != "" && r.resourceType != t {
+			continue
+		}
+		if r.pattern.MatchString(url) {
+			return r
+		}
+	}
+	return nil
+}
+
+// action 生成处理暂停请求的命令
+func (r *fetchRule) action(ev *fetch.EventRequestPaused) chromedp.Action {
+	switch r.Action {
+	case models.FetchMock:
+		body := r.Body
+		if !r.BodyBase64 {
+			body = base64.StdEncoding.EncodeToString([]byte(body))
+		}
+		return fetch.FulfillRequest(ev.RequestID, int64(r.Status)).
+			WithResponseHeaders(headerEntries(nil, r.Headers)).
+			WithBody(body)
+	case models.FetchHeaders:
+		return fetch.ContinueRequest(ev.RequestID).
+			WithHeaders(headerEntries(ev.Request.Headers, r.Headers))
+	case models.FetchRedirect:
+		if r.Status >= 300 && r.Status < 400 {
+			return fetch.FulfillRequest(ev.RequestID, int64(r.Status)).
+				WithResponseHeaders([]*fetch.HeaderEntry{{Name: "Location", Value: r.RedirectURL}})
+		}
+		return fetch.ContinueRequest(ev.RequestID).WithURL(r.RedirectURL)
+	}
+	return fetch.ContinueRequest(ev.RequestID)
+}
+
+// headerEntries 合并原有的 header 和修改的 header，名称不区分大小写，值为空时删除
+func headerEntries(origin network.Headers, changes map[string]string) []*fetch.HeaderEntry {
+	merged := make(map[string]fetch.HeaderEntry)
+	for k, v := range origin {
+		merged[strings.ToLower(k)] = fetch.HeaderEntry{Name: k, Value: fmt.Sprint(v)}
+	}
+	for k, v := range changes {
+		if v == "" {
+			delete(merged, strings.ToLower(k))
+			continue
+		}
+		merged[strings.ToLower(k)] = fetch.HeaderEntry{Name: k, Value: v}
+	}
+
+	keys := make([]string, 0, len(merged))
+	for k := range merged {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	entries := make([]*fetch.HeaderEntry, 0, len(keys))
+	for _, k := range keys {
+		e := merged[k]
+		entries = append(entries, &e)
+	}
+	return entries
+}
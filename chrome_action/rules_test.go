@@ -0,0 +1,121 @@
+package chrome_action
+
+import (
+	"github.com/LubyRuffy/chrome_proxy/models"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCompileFetchRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []models.FetchRule
+		wantErr bool
+	}{
+		{
+			name: "测试正常规则",
+			rules: []models.FetchRule{
+				{URLPattern: "*/api/*", Action: models.FetchMock, Body: "{}"},
+				{URLPattern: "*", ResourceType: "document", Action: models.FetchHeaders, Headers: map[string]string{"X-Test": "1"}},
+				{URLPattern: "*/login", Action: models.FetchRedirect, RedirectURL: "http://127.0.0.1:8080/login"},
+			},
+		},
+		{
+			name:    "测试缺少url_pattern",
+			rules:   []models.FetchRule{{Action: models.FetchMock}},
+			wantErr: true,
+		},
+		{
+			name:    "测试未知动作",
+			rules:   []models.FetchRule{{URLPattern: "*", Action: "drop"}},
+			wantErr: true,
+		},
+		{
+			name:    "测试错误的状态码",
+			rules:   []models.FetchRule{{URLPattern: "*", Action: models.FetchMock, Status: 1000}},
+			wantErr: true,
+		},
+		{
+			name:    "测试错误的base64",
+			rules:   []models.FetchRule{{URLPattern: "*", Action: models.FetchMock, Body: "!!", BodyBase64: true}},
+			wantErr: true,
+		},
+		{
+			name:    "测试redirect缺少地址",
+			rules:   []models.FetchRule{{URLPattern: "*", Action: models.FetchRedirect}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := compileFetchRules(tt.rules)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Len(t, got, len(tt.rules))
+		})
+	}
+}
+
+func TestFetchRule_action(t *testing.T) {
+	ev := &fetch.EventRequestPaused{
+		RequestID:    "1",
+		Request:      &network.Request{URL: "https://a.com/api/user", Headers: network.Headers{"User-Agent": "ua", "Referer": "https://a.com/"}},
+		ResourceType: network.ResourceTypeXHR,
+	}
+	tests := []struct {
+		name string
+		rule models.FetchRule
+		want chromedp.Action
+	}{
+		{
+			name: "测试mock",
+			rule: models.FetchRule{URLPattern: "*", Action: models.FetchMock, Headers: map[string]string{"Content-Type": "application/json"}, Body: `{"ok":true}`},
+			want: fetch.FulfillRequest("1", 200).
+				WithResponseHeaders([]*fetch.HeaderEntry{{Name: "Content-Type", Value: "application/json"}}).
+				WithBody("eyJvayI6dHJ1ZX0="),
+		},
+		{
+			name: "测试修改请求头",
+			rule: models.FetchRule{URLPattern: "*", Action: models.FetchHeaders, Headers: map[string]string{"referer": "", "X-Token": "abc"}},
+			want: fetch.ContinueRequest("1").WithHeaders([]*fetch.HeaderEntry{
+				{Name: "User-Agent", Value: "ua"},
+				{Name: "X-Token", Value: "abc"},
+			}),
+		},
+		{
+			name: "测试透明替换地址",
+			rule: models.FetchRule{URLPattern: "*", Action: models.FetchRedirect, RedirectURL: "http://127.0.0.1/api/user"},
+			want: fetch.ContinueRequest("1").WithURL("http://127.0.0.1/api/user"),
+		},
+		{
+			name: "测试302跳转",
+			rule: models.FetchRule{URLPattern: "*", Action: models.FetchRedirect, Status: 302, RedirectURL: "http://127.0.0.1/"},
+			want: fetch.FulfillRequest("1", 302).
+				WithResponseHeaders([]*fetch.HeaderEntry{{Name: "Location", Value: "http://127.0.0.1/"}}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := compileFetchRules([]models.FetchRule{tt.rule})
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, rules[0].action(ev))
+		})
+	}
+}
+
+func TestMatchFetchRule(t *testing.T) {
+	rules, err := compileFetchRules([]models.FetchRule{
+		{URLPattern: "*/api/*", ResourceType: "fetch", Action: models.FetchMock},
+		{URLPattern: "*/api/*", Action: models.FetchRedirect, RedirectURL: "http://127.0.0.1/"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, rules[0], matchFetchRule(rules, "https://a.com/api/x", network.ResourceTypeFetch))
+	assert.Equal(t, rules[1], matchFetchRule(rules, "https://a.com/api/x", network.ResourceTypeXHR))
+	assert.Nil(t, matchFetchRule(rules, "https://a.com/", network.ResourceTypeDocument))
+}
@@ -0,0 +1,85 @@
+package chrome_action
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/LubyRuffy/chrome_proxy/models"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"strings"
+)
+
+// scriptResultVar before_load 模式下保存脚本结果的全局变量
+const scriptResultVar = "__chromeProxyScriptResult"
+
+// beforeLoadScript 包装页面加载前注入的脚本，执行结果（或异常）保存到全局变量中，页面加载后再读取
+func beforeLoadScript(script string) string {
+	code, _ := json.Marshal(script)
+	return fmt.Sprintf(`window.%s = (function () {
+	try {
+		return Promise.resolve((0, eval)(%s));
+	} catch (e) {
+		return Promise.reject(e);
+	}
+})();`, scriptResultVar, code)
+}
+
+// scriptActions 生成执行自定义脚本的动作，before 在导航前执行，after 在页面加载后执行
+// 脚本出错不影响渲染，错误信息记录在 out.ScriptError 中
+func scriptActions(in models.ChromeActionInput, out *models.ChromeActionOutput) (before []chromedp.Action, after []chromedp.Action, err error) {
+	if in.Script == "" {
+		return nil, nil, nil
+	}
+
+	expression := in.Script
+	switch strings.ToLower(in.ScriptTiming) {
+	case "", models.ScriptTimingAfterLoad:
+	case models.ScriptTimingBeforeLoad:
+		// 脚本通过 eval 执行，需要绕过页面的 CSP 限制
+		before = append(before,
+			page.SetBypassCSP(true),
+			chromedp.ActionFunc(func(ctx context.Context) error {
+				_, err := page.AddScriptToEvaluateOnNewDocument(beforeLoadScript(in.Script)).Do(ctx)
+				return err
+			}),
+		)
+		expression = "window." + scriptResultVar
+	default:
+		return nil, nil, fmt.Errorf("unknown script timing: %s", in.ScriptTiming)
+	}
+
+	after = append(after, chromedp.ActionFunc(func(ctx context.Context) error {
+		result, err := evaluate(ctx, expression)
+		if err != nil {
+			out.ScriptError = err.Error()
+			return nil
+		}
+		out.ScriptResult = result
+		return nil
+	}))
+	return before, after, nil
+}
+
+// evaluate 执行 js 并等待 Promise 完成，返回结果的 json，无法序列化的值（如 undefined、dom节点）返回 null
+func evaluate(ctx context.Context, expression string) (json.RawMessage, error) {
+	v, exp, err := runtime.Evaluate(expression).
+		WithAwaitPromise(true).
+		WithReturnByValue(true).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if exp != nil {
+		if exp.Exception != nil && exp.Exception.Description != "" {
+			return nil, errors.New(exp.Exception.Description)
+		}
+		return nil, exp
+	}
+	if v == nil || len(v.Value) == 0 {
+		return json.RawMessage("null"), nil
+	}
+	return json.RawMessage(v.Value), nil
+}
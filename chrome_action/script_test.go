@@ -0,0 +1,57 @@
+package chrome_action
+
+import (
+	"github.com/LubyRuffy/chrome_proxy/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestScriptActions(t *testing.T) {
+	tests := []struct {
+		name       string
+		in         models.ChromeActionInput
+		wantBefore int
+		wantAfter  int
+		wantErr    bool
+	}{
+		{
+			name: "测试没有脚本",
+			in:   models.ChromeActionInput{ScriptTiming: models.ScriptTimingBeforeLoad},
+		},
+		{
+			name:      "测试默认页面加载后执行",
+			in:        models.ChromeActionInput{Script: "document.title"},
+			wantAfter: 1,
+		},
+		{
+			name:       "测试页面加载前注入",
+			in:         models.ChromeActionInput{Script: "1+1", ScriptTiming: "BEFORE_LOAD"},
+			wantBefore: 2,
+			wantAfter:  1,
+		},
+		{
+			name:    "测试未知执行时机",
+			in:      models.ChromeActionInput{Script: "1+1", ScriptTiming: "onload"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, after, err := scriptActions(tt.in, &models.ChromeActionOutput{})
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Len(t, before, tt.wantBefore)
+			assert.Len(t, after, tt.wantAfter)
+		})
+	}
+}
+
+func TestBeforeLoadScript(t *testing.T) {
+	got := beforeLoadScript(`document.querySelector("#a").click()`)
+	assert.Contains(t, got, "window."+scriptResultVar+" = ")
+	// 脚本作为字符串传给 eval，引号需要转义
+	assert.Contains(t, got, `(0, eval)("document.querySelector(\"#a\").click()")`)
+}
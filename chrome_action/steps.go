@@ -0,0 +1,265 @@
+package chrome_action
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/LubyRuffy/chrome_proxy/models"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/cdproto/input"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"github.com/chromedp/chromedp/kb"
+	"strings"
+	"sync"
+	"time"
+)
+
+// keyNames press 支持的按键名称，其他值按原样逐个字符输入
+var keyNames = map[string]string{
+	"enter":      kb.Enter,
+	"tab":        kb.Tab,
+	"escape":     kb.Escape,
+	"esc":        kb.Escape,
+	"backspace":  kb.Backspace,
+	"delete":     kb.Delete,
+	"space":      " ",
+	"arrowup":    kb.ArrowUp,
+	"arrowdown":  kb.ArrowDown,
+	"arrowleft":  kb.ArrowLeft,
+	"arrowright": kb.ArrowRight,
+	"home":       kb.Home,
+	"end":        kb.End,
+	"pageup":     kb.PageUp,
+	"pagedown":   kb.PageDown,
+}
+
+// validateSteps 在启动浏览器之前检查步骤参数
+func validateSteps(steps []models.Step) error {
+	for i, step := range steps {
+		switch step.Action {
+		case models.StepClick, models.StepType, models.StepHover, models.StepSelect, models.StepWaitSelector:
+			if step.Selector == "" {
+				return fmt.Errorf("step %d: %s requires selector", i, step.Action)
+			}
+		case models.StepPress:
+			if step.Value == "" {
+				return fmt.Errorf("step %d: press requires value", i)
+			}
+		case models.StepScroll, models.StepWaitNavigation, models.StepSleep:
+		default:
+			return fmt.Errorf("step %d: unknown action: %s", i, step.Action)
+		}
+	}
+	return nil
+}
+
+// stepRunner 依次执行交互步骤，并记录页面 load 事件用于 wait_navigation
+type stepRunner struct {
+	mu     sync.Mutex
+	loads  int
+	loaded chan struct{} // 每次 load 事件时关闭并替换
+}
+
+// OnEvent 处理 chromedp.ListenTarget 的事件
+func (r *stepRunner) OnEvent(ev interface{}) {
+	if _, ok := ev.(*page.EventLoadEventFired); ok {
+		r.mu.Lock()
+		r.loads++
+		close(r.loaded)
+		r.loaded = make(chan struct{})
+		r.mu.Unlock()
+	}
+}
+
+// waitLoad 等待 load 事件次数超过 n
+func (r *stepRunner) waitLoad(ctx context.Context, n int) error {
+	for {
+		r.mu.Lock()
+		loads, loaded := r.loads, r.loaded
+		r.mu.Unlock()
+		if loads > n {
+			return nil
+		}
+
+		select {
+		case <-loaded:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (r *stepRunner) loadCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.loads
+}
+
+// stepsAction 执行交互步骤，结果记录在 out.Steps 中，步骤失败不影响后续的截图等动作
+func stepsAction(steps []models.Step, out *models.ChromeActionOutput) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		r := &stepRunner{loaded: make(chan struct{})}
+		chromedp.ListenTarget(ctx, r.OnEvent)
+
+		// 上一步开始前的 load 次数，wait_navigation 等待上一步触发的跳转
+		prevLoads := r.loadCount()
+		for _, step := range steps {
+			loads := r.loadCount()
+			err := r.run(ctx, step, prevLoads)
+			prevLoads = loads
+
+			result := models.StepResult{Action: step.Action}
+			if err != nil {
+				result.Error = err.Error()
+			}
+			out.Steps = append(out.Steps, result)
+
+			// 整体超时后直接结束
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil && !step.Optional {
+				break
+			}
+		}
+		return nil
+	})
+}
+
+func (r *stepRunner) run(ctx context.Context, step models.Step, prevLoads int) error {
+	if step.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(step.Timeout*float64(time.Second)))
+		defer cancel()
+	}
+
+	by := chromedp.ByQuery
+	if step.SelectorType == models.SelectorXPath {
+		by = chromedp.BySearch
+	}
+
+	var err error
+	switch step.Action {
+	case models.StepClick:
+		err = chromedp.Click(step.Selector, by, chromedp.NodeVisible).Do(ctx)
+	case models.StepType:
+		err = chromedp.SendKeys(step.Selector, step.Value, by, chromedp.NodeVisible).Do(ctx)
+	case models.StepPress:
+		if step.Selector != "" {
+			if err = chromedp.Focus(step.Selector, by, chromedp.NodeVisible).Do(ctx); err != nil {
+				break
+			}
+		}
+		keys, ok := keyNames[strings.ToLower(step.Value)]
+		if !ok {
+			keys = step.Value
+		}
+		err = chromedp.KeyEvent(keys).Do(ctx)
+	case models.StepScroll:
+		if step.Selector != "" {
+			err = chromedp.ScrollIntoView(step.Selector, by).Do(ctx)
+			break
+		}
+		_, exp, err2 := runtime.Evaluate(fmt.Sprintf("window.scrollBy(%d, %d)", step.X, step.Y)).Do(ctx)
+		if err = err2; err == nil && exp != nil {
+			err = exp
+		}
+	case models.StepHover:
+		err = hover(ctx, step.Selector, by)
+	case models.StepSelect:
+		err = selectOption(ctx, step.Selector, by, step.Value)
+	case models.StepWaitSelector:
+		err = chromedp.WaitVisible(step.Selector, by).Do(ctx)
+	case models.StepWaitNavigation:
+		err = r.waitLoad(ctx, prevLoads)
+	case models.StepSleep:
+		err = chromedp.Sleep(time.Duration(step.Duration * float64(time.Second))).Do(ctx)
+	default:
+		err = fmt.Errorf("unknown action: %s", step.Action)
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%s timeout", step.Action)
+	}
+	return err
+}
+
+// callOnNode 在第一个匹配的可见元素上执行 js 函数，函数中 this 为该元素
+func callOnNode(ctx context.Context, selector string, by chromedp.QueryOption, fn string, res interface{}, args ...interface{}) error {
+	var nodes []*cdp.Node
+	if err := chromedp.Nodes(selector, &nodes, by, chromedp.NodeVisible).Do(ctx); err != nil {
+		return err
+	}
+	obj, err := dom.ResolveNode().WithNodeID(nodes[0].NodeID).Do(ctx)
+	if err != nil {
+		return err
+	}
+
+	var callArgs []*runtime.CallArgument
+	for _, arg := range args {
+		v, err := json.Marshal(arg)
+		if err != nil {
+			return err
+		}
+		callArgs = append(callArgs, &runtime.CallArgument{Value: v})
+	}
+
+	ret, exp, err := runtime.CallFunctionOn(fn).
+		WithObjectID(obj.ObjectID).
+		WithArguments(callArgs).
+		WithReturnByValue(true).
+		Do(ctx)
+	if err != nil {
+		return err
+	}
+	if exp != nil {
+		return exp
+	}
+	if res == nil {
+		return nil
+	}
+	return json.Unmarshal(ret.Value, res)
+}
+
+// hover 将鼠标移动到元素中心
+func hover(ctx context.Context, selector string, by chromedp.QueryOption) error {
+	var center struct {
+		X float64 `json:"x"`
+		Y float64 `json:"y"`
+	}
+	err := callOnNode(ctx, selector, by, `function() {
+	this.scrollIntoView({block: "center", inline: "center"});
+	const r = this.getBoundingClientRect();
+	return {x: r.left + r.width / 2, y: r.top + r.height / 2};
+}`, &center)
+	if err != nil {
+		return err
+	}
+	return chromedp.MouseEvent(input.MouseMoved, center.X, center.Y).Do(ctx)
+}
+
+// selectOption 选中下拉框中 value 或者文本匹配的选项，并触发 input、change 事件
+func selectOption(ctx context.Context, selector string, by chromedp.QueryOption, value string) error {
+	var found bool
+	err := callOnNode(ctx, selector, by, `function(v) {
+	const opt = Array.from(this.options || []).find(o => o.value === v || o.text.trim() === v);
+	if (!opt) {
+		return false;
+	}
+	this.value = opt.value;
+	this.dispatchEvent(new Event("input", {bubbles: true}));
+	this.dispatchEvent(new Event("change", {bubbles: true}));
+	return true;
+}`, &found, value)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("option not found: %s", value)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package chrome_action
+
+import (
+	"context"
+	"github.com/LubyRuffy/chrome_proxy/models"
+	"github.com/chromedp/cdproto/page"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestValidateSteps(t *testing.T) {
+	tests := []struct {
+		name    string
+		steps   []models.Step
+		wantErr bool
+	}{
+		{
+			name: "测试登录流程",
+			steps: []models.Step{
+				{Action: models.StepType, Selector: "#user", Value: "admin"},
+				{Action: models.StepType, Selector: "#pass", Value: "123456"},
+				{Action: models.StepPress, Value: "Enter"},
+				{Action: models.StepWaitNavigation, Timeout: 5},
+				{Action: models.StepScroll, Y: 500},
+				{Action: models.StepSleep, Duration: 0.5},
+			},
+		},
+		{
+			name:    "测试缺少selector",
+			steps:   []models.Step{{Action: models.StepClick}},
+			wantErr: true,
+		},
+		{
+			name:    "测试缺少按键",
+			steps:   []models.Step{{Action: models.StepPress, Selector: "#q"}},
+			wantErr: true,
+		},
+		{
+			name:    "测试未知动作",
+			steps:   []models.Step{{Action: "drag", Selector: "#a"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSteps(tt.steps)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func TestStepRunner_waitLoad(t *testing.T) {
+	r := &stepRunner{loaded: make(chan struct{})}
+
+	// 上一步之后已经发生的 load 事件直接返回
+	r.OnEvent(&page.EventLoadEventFired{})
+	assert.Nil(t, r.waitLoad(context.Background(), 0))
+
+	// 等待新的 load 事件
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		r.OnEvent(&page.EventLoadEventFired{})
+	}()
+	assert.Nil(t, r.waitLoad(context.Background(), 1))
+	assert.Equal(t, 2, r.loadCount())
+
+	// 超时
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, r.waitLoad(ctx, 2), context.DeadlineExceeded)
+}
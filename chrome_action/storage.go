@@ -0,0 +1,134 @@
+package chrome_action
+
+import (
+	"context"
+	"github.com/LubyRuffy/chrome_proxy/models"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/domstorage"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/storage"
+	"github.com/chromedp/chromedp"
+	"net/url"
+	"strings"
+)
+
+// storageAction 获取 cookie 以及页面中各个 origin 的 localStorage、sessionStorage
+// 获取失败时记录到 out.StorageError 并返回已获取的部分，不影响截图等主要结果
+func storageAction(out *models.ChromeActionOutput) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		result := &models.Storage{
+			Cookies: []models.Cookie{},
+			Origins: []models.OriginStorage{},
+		}
+		out.Storage = result
+
+		var errs []string
+		cookies, err := contextCookies(ctx)
+		if err != nil {
+			errs = append(errs, "get cookies: "+err.Error())
+		} else {
+			result.Cookies = convertCookies(cookies)
+		}
+
+		tree, err := page.GetFrameTree().Do(ctx)
+		if err == nil {
+			err = domstorage.Enable().Do(ctx)
+		}
+		if err != nil {
+			errs = append(errs, "get frames: "+err.Error())
+			out.StorageError = strings.Join(errs, "; ")
+			return nil
+		}
+
+		for _, origin := range frameOrigins(tree) {
+			local, err := domstorage.GetDOMStorageItems(&domstorage.StorageID{SecurityOrigin: origin, IsLocalStorage: true}).Do(ctx)
+			if err != nil {
+				errs = append(errs, origin+": "+err.Error())
+				continue
+			}
+			session, err := domstorage.GetDOMStorageItems(&domstorage.StorageID{SecurityOrigin: origin}).Do(ctx)
+			if err != nil {
+				errs = append(errs, origin+": "+err.Error())
+				continue
+			}
+			result.Origins = append(result.Origins, models.OriginStorage{
+				Origin:         origin,
+				LocalStorage:   storageItems(local),
+				SessionStorage: storageItems(session),
+			})
+		}
+
+		out.StorageError = strings.Join(errs, "; ")
+		return nil
+	})
+}
+
+// contextCookies 获取页面所在浏览器上下文的全部 cookie
+// storage.getCookies 是浏览器级别的命令，不指定 browserContextId 时返回默认上下文的 cookie，
+// 浏览器池中的页面位于单独的隐身上下文，需要在浏览器上指定上下文执行
+func contextCookies(ctx context.Context) ([]*network.Cookie, error) {
+	c := chromedp.FromContext(ctx)
+	if c == nil || c.Browser == nil {
+		return nil, chromedp.ErrInvalidContext
+	}
+	params := storage.GetCookies()
+	if c.BrowserContextID != "" {
+		params = params.WithBrowserContextID(c.BrowserContextID)
+	}
+	return params.Do(cdp.WithExecutor(ctx, c.Browser))
+}
+
+// convertCookies 转换 chrome 的 cookie，会话 cookie 没有过期时间
+func convertCookies(cookies []*network.Cookie) []models.Cookie {
+	result := []models.Cookie{}
+	for _, c := range cookies {
+		cookie := models.Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Secure:   c.Secure,
+			HTTPOnly: c.HTTPOnly,
+			SameSite: string(c.SameSite),
+		}
+		if !c.Session {
+			cookie.Expires = c.Expires
+		}
+		result = append(result, cookie)
+	}
+	return result
+}
+
+// frameOrigins 返回 frame 树中去重后的 origin，about:blank 等不透明 origin（"://"、"null"）没有存储
+func frameOrigins(tree *page.FrameTree) []string {
+	var origins []string
+	seen := make(map[string]bool)
+
+	var walk func(*page.FrameTree)
+	walk = func(t *page.FrameTree) {
+		if t == nil {
+			return
+		}
+		origin := t.Frame.SecurityOrigin
+		if u, err := url.Parse(origin); err == nil && u.Host != "" && !seen[origin] {
+			seen[origin] = true
+			origins = append(origins, origin)
+		}
+		for _, child := range t.ChildFrames {
+			walk(child)
+		}
+	}
+	walk(tree)
+	return origins
+}
+
+func storageItems(items []domstorage.Item) map[string]string {
+	result := make(map[string]string, len(items))
+	for _, item := range items {
+		if len(item) == 2 {
+			result[item[0]] = item[1]
+		}
+	}
+	return result
+}
@@ -0,0 +1,45 @@
+package chrome_action
+
+import (
+	"github.com/LubyRuffy/chrome_proxy/models"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/domstorage"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestConvertCookies(t *testing.T) {
+	got := convertCookies([]*network.Cookie{
+		{Name: "sid", Value: "1", Domain: ".a.com", Path: "/", Expires: -1, Session: true, HTTPOnly: true},
+		{Name: "uid", Value: "2", Domain: "a.com", Path: "/", Expires: 1700000000, Secure: true, SameSite: network.CookieSameSiteNone},
+	})
+	assert.Equal(t, []models.Cookie{
+		{Name: "sid", Value: "1", Domain: ".a.com", Path: "/", HTTPOnly: true},
+		{Name: "uid", Value: "2", Domain: "a.com", Path: "/", Expires: 1700000000, Secure: true, SameSite: "None"},
+	}, got)
+	assert.Equal(t, []models.Cookie{}, convertCookies(nil))
+}
+
+func TestFrameOrigins(t *testing.T) {
+	tree := &page.FrameTree{
+		Frame: &cdp.Frame{SecurityOrigin: "https://a.com"},
+		ChildFrames: []*page.FrameTree{
+			{Frame: &cdp.Frame{SecurityOrigin: "https://ads.com"}},
+			{Frame: &cdp.Frame{SecurityOrigin: "://"}},
+			{
+				Frame: &cdp.Frame{SecurityOrigin: "https://a.com"},
+				ChildFrames: []*page.FrameTree{
+					{Frame: &cdp.Frame{SecurityOrigin: "https://b.com:8443"}},
+				},
+			},
+		},
+	}
+	assert.Equal(t, []string{"https://a.com", "https://ads.com", "https://b.com:8443"}, frameOrigins(tree))
+}
+
+func TestStorageItems(t *testing.T) {
+	got := storageItems([]domstorage.Item{{"token", "abc"}, {"theme", "dark"}, {"broken"}})
+	assert.Equal(t, map[string]string{"token": "abc", "theme": "dark"}, got)
+}
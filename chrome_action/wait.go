@@ -0,0 +1,222 @@
+package chrome_action
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/LubyRuffy/chrome_proxy/models"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultNetworkIdleTime networkidle 默认要求没有请求的时间
+	defaultNetworkIdleTime = 500 * time.Millisecond
+	// waitPollInterval 轮询 networkidle、function 的间隔
+	waitPollInterval = 100 * time.Millisecond
+)
+
+// waitStrategy 检查并返回页面就绪条件
+func waitStrategy(in models.ChromeActionInput) (string, error) {
+	strategy := strings.ToLower(in.WaitUntil)
+	if strategy == "" {
+		switch {
+		case in.WaitSelector != "":
+			strategy = models.WaitSelector
+		case in.WaitFunction != "":
+			strategy = models.WaitFunction
+		default:
+			strategy = models.WaitLoad
+		}
+	}
+
+	switch strategy {
+	case models.WaitLoad, models.WaitDOMContentLoaded, models.WaitNetworkIdle:
+	case models.WaitSelector:
+		if in.WaitSelector == "" {
+			return "", fmt.Errorf("wait_until selector requires wait_selector")
+		}
+	case models.WaitFunction:
+		if in.WaitFunction == "" {
+			return "", fmt.Errorf("wait_until function requires wait_function")
+		}
+	default:
+		return "", fmt.Errorf("unknown wait_until: %s", in.WaitUntil)
+	}
+	return strategy, nil
+}
+
+// navigateAction 打开页面，按 strategy 等待页面就绪后再休眠 in.Sleep 秒
+func navigateAction(in models.ChromeActionInput, strategy string) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		switch strategy {
+		case models.WaitDOMContentLoaded:
+			err = navigateDOMContentLoaded(ctx, in.URL)
+		case models.WaitNetworkIdle:
+			idle := defaultNetworkIdleTime
+			if in.NetworkIdleTime > 0 {
+				idle = time.Duration(in.NetworkIdleTime) * time.Millisecond
+			}
+			err = navigateNetworkIdle(ctx, in.URL, idle)
+		case models.WaitSelector:
+			by := chromedp.ByQuery
+			if in.WaitSelectorType == models.SelectorXPath {
+				by = chromedp.BySearch
+			}
+			err = chromedp.Run(ctx,
+				chromedp.Navigate(in.URL),
+				chromedp.WaitVisible(in.WaitSelector, by),
+			)
+		case models.WaitFunction:
+			if err = chromedp.Navigate(in.URL).Do(ctx); err == nil {
+				err = waitFunction(ctx, in.WaitFunction)
+			}
+		default:
+			err = chromedp.Navigate(in.URL).Do(ctx)
+		}
+		if err != nil {
+			return err
+		}
+
+		return chromedp.Sleep(time.Duration(in.Sleep) * time.Second).Do(ctx)
+	})
+}
+
+// navigateDOMContentLoaded 打开页面，DOMContentLoaded 后即返回，不等待图片等资源加载完成
+func navigateDOMContentLoaded(ctx context.Context, url string) error {
+	lctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// 必须在导航之前监听，事件可能在 Navigate 返回之前触发
+	fired := make(chan struct{})
+	var once sync.Once
+	chromedp.ListenTarget(lctx, func(ev interface{}) {
+		if _, ok := ev.(*page.EventDomContentEventFired); ok {
+			once.Do(func() { close(fired) })
+		}
+	})
+
+	_, _, errorText, err := page.Navigate(url).Do(ctx)
+	if err != nil {
+		return err
+	}
+	if errorText != "" {
+		return fmt.Errorf("page load error %s", errorText)
+	}
+
+	select {
+	case <-fired:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// idleTracker 记录进行中的请求
+type idleTracker struct {
+	mu         sync.Mutex
+	inflight   map[network.RequestID]bool
+	lastChange time.Time
+}
+
+func newIdleTracker() *idleTracker {
+	return &idleTracker{
+		inflight:   make(map[network.RequestID]bool),
+		lastChange: time.Now(),
+	}
+}
+
+// OnEvent 处理 chromedp.ListenTarget 的事件
+func (t *idleTracker) OnEvent(ev interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch ev := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		// EventSource 长连接不会结束
+		if ev.Type == network.ResourceTypeEventSource {
+			return
+		}
+		t.inflight[ev.RequestID] = true
+	case *network.EventLoadingFinished:
+		delete(t.inflight, ev.RequestID)
+	case *network.EventLoadingFailed:
+		delete(t.inflight, ev.RequestID)
+	default:
+		return
+	}
+	t.lastChange = time.Now()
+}
+
+// idle 判断是否已经有 d 时间没有进行中的请求
+func (t *idleTracker) idle(now time.Time, d time.Duration) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.inflight) == 0 && now.Sub(t.lastChange) >= d
+}
+
+// navigateNetworkIdle 打开页面，等待 load 事件后持续 idle 时间没有进行中的请求
+func navigateNetworkIdle(ctx context.Context, url string, idle time.Duration) error {
+	lctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	tracker := newIdleTracker()
+	chromedp.ListenTarget(lctx, tracker.OnEvent)
+
+	if err := chromedp.Navigate(url).Do(ctx); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(waitPollInterval)
+	defer ticker.Stop()
+	for {
+		if tracker.idle(time.Now(), idle) {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// predicateExpression 包装 wait_function，函数会被调用，结果转换为 bool
+func predicateExpression(predicate string) string {
+	return fmt.Sprintf(`(async function () {
+	let r = (%s);
+	if (typeof r === "function") {
+		r = r();
+	}
+	return !!(await r);
+})()`, predicate)
+}
+
+// waitFunction 轮询直到 predicate 返回 true，执行出错（如变量尚未定义）时继续等待
+func waitFunction(ctx context.Context, predicate string) error {
+	expression := predicateExpression(predicate)
+	for {
+		v, exp, err := runtime.Evaluate(expression).
+			WithAwaitPromise(true).
+			WithReturnByValue(true).
+			Do(ctx)
+		if err == nil && exp == nil && v != nil {
+			var ok bool
+			if json.Unmarshal(v.Value, &ok) == nil && ok {
+				return nil
+			}
+		}
+
+		select {
+		case <-time.After(waitPollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
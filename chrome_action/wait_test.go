@@ -0,0 +1,74 @@
+package chrome_action
+
+import (
+	"github.com/LubyRuffy/chrome_proxy/models"
+	"github.com/chromedp/cdproto/network"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestWaitStrategy(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      models.ChromeActionInput
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "测试默认load",
+			in:   models.ChromeActionInput{},
+			want: models.WaitLoad,
+		},
+		{
+			name: "测试大小写",
+			in:   models.ChromeActionInput{WaitUntil: "DOMContentLoaded"},
+			want: models.WaitDOMContentLoaded,
+		},
+		{
+			name: "测试根据wait_selector推断",
+			in:   models.ChromeActionInput{WaitSelector: "#app"},
+			want: models.WaitSelector,
+		},
+		{
+			name: "测试根据wait_function推断",
+			in:   models.ChromeActionInput{WaitFunction: "window.ready"},
+			want: models.WaitFunction,
+		},
+		{
+			name:    "测试selector缺少参数",
+			in:      models.ChromeActionInput{WaitUntil: models.WaitSelector},
+			wantErr: true,
+		},
+		{
+			name:    "测试未知条件",
+			in:      models.ChromeActionInput{WaitUntil: "networkidle2"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := waitStrategy(tt.in)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestIdleTracker(t *testing.T) {
+	tracker := newIdleTracker()
+	start := tracker.lastChange
+
+	tracker.OnEvent(&network.EventRequestWillBeSent{RequestID: "1", Type: network.ResourceTypeDocument})
+	tracker.OnEvent(&network.EventRequestWillBeSent{RequestID: "2", Type: network.ResourceTypeEventSource})
+	assert.False(t, tracker.idle(start.Add(time.Second), 500*time.Millisecond))
+
+	tracker.OnEvent(&network.EventLoadingFinished{RequestID: "1"})
+	last := tracker.lastChange
+	assert.False(t, tracker.idle(last.Add(100*time.Millisecond), 500*time.Millisecond))
+	assert.True(t, tracker.idle(last.Add(500*time.Millisecond), 500*time.Millisecond))
+}
@@ -0,0 +1,58 @@
+package extract
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"github.com/LubyRuffy/chrome_proxy/chrome_action"
+	"github.com/LubyRuffy/chrome_proxy/models"
+	"github.com/chromedp/chromedp"
+	"log"
+)
+
+// extractScript 在页面中提取内容的脚本，返回 models.Extraction 对应的 json
+//
+//go:embed extract.js
+var extractScript string
+
+// Extract 渲染单个url后提取可见文本、正文、链接、表单和元数据
+func Extract(options *models.ChromeParam) (*models.ExtractOutput, error) {
+	return ExtractContext(context.Background(), options)
+}
+
+// ExtractContext 渲染单个url后提取页面内容，ctx 取消时中止
+func ExtractContext(ctx context.Context, options *models.ChromeParam) (*models.ExtractOutput, error) {
+	log.Println("Extract of url:", options.URL)
+
+	actions, finish := Actions(options)
+	out, err := chrome_action.ChromeActionsContext(ctx, options.ChromeActionInput, func(s string, i ...interface{}) {
+
+	}, options.Timeout, nil, actions...)
+	if err != nil {
+		return nil, fmt.Errorf("Extract failed(%w): %s", err, options.URL)
+	}
+	return finish(out)
+}
+
+// Actions 生成提取页面内容需要在页面中执行的动作，渲染完成后调用 finish 生成结果
+// 用于与其他动作共享同一次渲染
+func Actions(options *models.ChromeParam) ([]chromedp.Action, func(out *models.ChromeActionOutput) (*models.ExtractOutput, error)) {
+	var extraction models.Extraction
+	var actions []chromedp.Action
+	actions = append(actions, chromedp.Evaluate(extractScript, &extraction))
+
+	var title string
+	actions = append(actions, chromedp.Title(&title))
+	var location string
+	actions = append(actions, chromedp.Location(&location))
+
+	return actions, func(out *models.ChromeActionOutput) (*models.ExtractOutput, error) {
+		return &models.ExtractOutput{
+			Extraction: extraction,
+			Title:      title,
+			Location:   location,
+
+			ChromeActionOutput: *out,
+		}, nil
+	}
+}
@@ -0,0 +1,164 @@
+// 在页面中提取文本、正文、链接、表单和元数据，返回值与 models.Extraction 对应
+(() => {
+  const clean = (s) => (s || '').replace(/\s+/g, ' ').trim();
+  const attr = (el, name) => el.getAttribute(name) || '';
+  const absolute = (href) => {
+    try {
+      return new URL(href, document.baseURI).href;
+    } catch (e) {
+      return href;
+    }
+  };
+  const visible = (el) => !!(el.offsetWidth || el.offsetHeight || el.getClientRects().length);
+
+  const links = Array.from(document.querySelectorAll('a[href], area[href]')).map((a) => ({
+    href: attr(a, 'href'),
+    url: a.href,
+    text: clean(a.innerText || a.textContent) || clean(attr(a, 'aria-label') || attr(a, 'title') || attr(a, 'alt') ||
+      Array.from(a.querySelectorAll('img[alt]')).map((img) => img.alt).join(' ')),
+    rel: attr(a, 'rel'),
+    target: attr(a, 'target'),
+  }));
+
+  const forms = Array.from(document.forms).map((form) => ({
+    id: attr(form, 'id'),
+    name: attr(form, 'name'),
+    action: absolute(attr(form, 'action') || document.URL),
+    method: (attr(form, 'method') || 'get').toLowerCase(),
+    enctype: attr(form, 'enctype'),
+    inputs: Array.from(form.elements)
+      .filter((el) => ['INPUT', 'SELECT', 'TEXTAREA'].includes(el.tagName))
+      .map((el) => ({
+        tag: el.tagName.toLowerCase(),
+        type: el.tagName === 'TEXTAREA' ? '' : (el.type || ''),
+        name: el.name || '',
+        id: el.id || '',
+        value: el.value || '',
+        placeholder: attr(el, 'placeholder'),
+        required: !!el.required,
+        hidden: el.type === 'hidden' || !visible(el),
+        options: el.tagName === 'SELECT' ? Array.from(el.options).map((o) => o.value) : undefined,
+      })),
+  }));
+
+  const meta = Array.from(document.querySelectorAll('meta')).map((m) => ({
+    name: attr(m, 'name') || attr(m, 'itemprop'),
+    property: attr(m, 'property'),
+    http_equiv: attr(m, 'http-equiv'),
+    charset: attr(m, 'charset'),
+    content: attr(m, 'content'),
+  }));
+  const metaContent = (...keys) => {
+    for (const key of keys) {
+      const m = meta.find((m) => (m.name || m.property).toLowerCase() === key && m.content);
+      if (m) return clean(m.content);
+    }
+    return '';
+  };
+
+  const openGraph = {};
+  for (const m of meta) {
+    const key = (m.property || m.name).toLowerCase();
+    if (key.startsWith('og:') && m.content && !(key in openGraph)) {
+      openGraph[key] = m.content;
+    }
+  }
+
+  const canonical = document.querySelector('link[rel~="canonical" i][href]');
+  const icon = document.querySelector('link[rel~="icon" i][href]') ||
+    document.querySelector('link[rel~="apple-touch-icon" i][href]');
+  let favicon = icon ? icon.href : '';
+  if (!favicon && /^https?:$/.test(location.protocol)) {
+    favicon = location.origin + '/favicon.ico';
+  }
+
+  // Readability 风格的正文识别：按段落文本给父节点打分，结合 class/id 和链接密度选出得分最高的节点
+  const content = (() => {
+    if (!document.body) return null;
+    const unlikely = /comment|footer|header|sidebar|nav|menu|banner|sponsor|ad-|ads|advert|share|social|related|popup|modal|cookie|breadcrumb|pagination|widget/i;
+    const positive = /article|body|content|entry|main|page|post|text|blog|story/i;
+    const negative = /comment|footer|sidebar|nav|menu|banner|sponsor|ad-|ads|advert|share|social|related|popup|modal|cookie|widget|hidden/i;
+    const skip = 'script,style,noscript,template,svg,nav,header,footer,aside,form,iframe,button,select';
+
+    const classWeight = (el) => {
+      let weight = 0;
+      for (const s of [el.className, el.id]) {
+        if (typeof s !== 'string' || !s) continue;
+        if (negative.test(s)) weight -= 25;
+        if (positive.test(s)) weight += 25;
+      }
+      return weight;
+    };
+    const linkDensity = (el) => {
+      const length = clean(el.innerText).length;
+      if (!length) return 0;
+      let linkLength = 0;
+      for (const a of el.querySelectorAll('a')) linkLength += clean(a.innerText).length;
+      return linkLength / length;
+    };
+
+    const scores = new Map();
+    const addScore = (el, score) => {
+      if (!el || el === document.documentElement) return;
+      if (!scores.has(el)) {
+        let init = classWeight(el);
+        if (/^(ARTICLE|MAIN)$/.test(el.tagName) || el.getAttribute('role') === 'main') init += 25;
+        if (/^(DIV|SECTION|ARTICLE|MAIN)$/.test(el.tagName)) init += 5;
+        scores.set(el, init);
+      }
+      scores.set(el, scores.get(el) + score);
+    };
+
+    for (const p of document.body.querySelectorAll('p,pre,td,blockquote,li,div')) {
+      if (p.closest(skip) || !visible(p)) continue;
+      // div 只统计直接包含文本的
+      if (p.tagName === 'DIV' && p.querySelector('p,div,pre,td,blockquote,li,table,ul,ol')) continue;
+      const parent = p.parentElement;
+      if (parent && parent !== document.body && unlikely.test(`${parent.className} ${parent.id}`) &&
+        !positive.test(`${parent.className} ${parent.id}`)) continue;
+      const text = clean(p.innerText);
+      if (text.length < 25) continue;
+      // 基础分1，每个逗号句号加1，每100字加1（最多3）
+      const score = text.split(/[,，、。；;]/).length + Math.min(Math.floor(text.length / 100), 3);
+      addScore(parent, score);
+      if (parent) addScore(parent.parentElement, score / 2);
+      if (parent && parent.parentElement) addScore(parent.parentElement.parentElement, score / 3);
+    }
+
+    let best = null;
+    let bestScore = 0;
+    for (const [el, score] of scores) {
+      const final = score * (1 - linkDensity(el));
+      if (final > bestScore) {
+        best = el;
+        bestScore = final;
+      }
+    }
+    if (!best) return null;
+
+    const text = (best.innerText || '').trim();
+    if (!text) return null;
+    const firstParagraph = Array.from(best.querySelectorAll('p')).map((p) => clean(p.innerText)).find((t) => t);
+    const heading = document.querySelector('h1');
+    return {
+      title: openGraph['og:title'] || clean(heading && heading.innerText) || clean(document.title),
+      byline: metaContent('author', 'article:author'),
+      excerpt: metaContent('description', 'og:description') || firstParagraph || '',
+      text: text,
+      html: best.innerHTML,
+      length: text.length,
+    };
+  })();
+
+  return {
+    text: document.body ? document.body.innerText : '',
+    content: content,
+    links: links,
+    forms: forms,
+    meta: meta,
+    open_graph: openGraph,
+    canonical: canonical ? canonical.href : '',
+    favicon: favicon,
+    lang: document.documentElement.lang || '',
+  };
+})()
@@ -0,0 +1,92 @@
+package extract
+
+import (
+	"github.com/LubyRuffy/chrome_proxy/models"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const samplePage = `<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>测试页面</title>
+<meta name="description" content="页面描述">
+<meta name="author" content="张三">
+<meta property="og:title" content="OG 标题">
+<meta property="og:image" content="/og.png">
+<link rel="canonical" href="/article/1">
+<link rel="shortcut icon" href="/static/icon.png">
+</head>
+<body>
+<nav><a href="/">首页</a> <a href="/about" rel="nofollow" target="_blank">关于</a></nav>
+<div class="sidebar"><a href="https://ads.example.com/"><img alt="广告"></a></div>
+<article class="post-content">
+<h1>文章标题</h1>
+<p>第一段正文，包含足够多的文字，用于识别正文内容，逗号，句号。</p>
+<p>第二段正文，同样包含足够多的文字，保证得分高于其他区域，逗号，句号。</p>
+</article>
+<form action="/login" method="POST">
+<input type="hidden" name="token" value="abc">
+<input type="text" name="user" placeholder="用户名" required>
+<input type="password" name="pass">
+<select name="lang"><option value="zh">中文</option><option value="en">English</option></select>
+<textarea name="note"></textarea>
+<button type="submit">登录</button>
+</form>
+</body>
+</html>`
+
+func TestExtract(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(samplePage))
+	}))
+	defer srv.Close()
+
+	out, err := Extract(&models.ChromeParam{
+		ChromeActionInput: models.ChromeActionInput{
+			URL:     srv.URL,
+			Timeout: 30,
+		},
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "测试页面", out.Title)
+	assert.Equal(t, "zh-CN", out.Lang)
+	assert.Contains(t, out.Text, "第一段正文")
+
+	// 正文
+	assert.NotNil(t, out.Content)
+	assert.Equal(t, "OG 标题", out.Content.Title)
+	assert.Equal(t, "张三", out.Content.Byline)
+	assert.Equal(t, "页面描述", out.Content.Excerpt)
+	assert.Contains(t, out.Content.Text, "第二段正文")
+	assert.NotContains(t, out.Content.Text, "首页")
+
+	// 链接
+	assert.Len(t, out.Links, 3)
+	assert.Equal(t, models.Link{Href: "/about", URL: srv.URL + "/about", Text: "关于", Rel: "nofollow", Target: "_blank"}, out.Links[1])
+	assert.Equal(t, "广告", out.Links[2].Text)
+
+	// 表单
+	assert.Len(t, out.Forms, 1)
+	form := out.Forms[0]
+	assert.Equal(t, srv.URL+"/login", form.Action)
+	assert.Equal(t, "post", form.Method)
+	assert.Len(t, form.Inputs, 5)
+	assert.Equal(t, models.FormInput{Tag: "input", Type: "hidden", Name: "token", Value: "abc", Hidden: true}, form.Inputs[0])
+	assert.True(t, form.Inputs[1].Required)
+	assert.Equal(t, "用户名", form.Inputs[1].Placeholder)
+	assert.Equal(t, []string{"zh", "en"}, form.Inputs[3].Options)
+	assert.Equal(t, "textarea", form.Inputs[4].Tag)
+
+	// 元数据
+	assert.Contains(t, out.Meta, models.MetaTag{Name: "author", Content: "张三"})
+	assert.Equal(t, map[string]string{"og:title": "OG 标题", "og:image": "/og.png"}, out.OpenGraph)
+	assert.Equal(t, srv.URL+"/article/1", out.Canonical)
+	assert.Equal(t, srv.URL+"/static/icon.png", out.Favicon)
+}
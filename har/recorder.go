@@ -0,0 +1,395 @@
+package har
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// entry 记录中的一次请求，重定向的每一跳都是单独的 entry
+type entry struct {
+	requestID network.RequestID
+	request   *network.Request
+	wallTime  time.Time
+	startTime time.Time
+	response  *network.Response
+	endTime   time.Time
+	finished  bool
+	failed    string
+	dataSize  float64
+	body      []byte
+	comment   string
+}
+
+// Recorder 通过 Network 事件记录页面加载过程中的所有请求，生成 HAR
+type Recorder struct {
+	maxBodySize int
+
+	mu            sync.Mutex
+	entries       []*entry
+	byID          map[network.RequestID]*entry
+	contentLoaded time.Time
+	loaded        time.Time
+}
+
+// NewRecorder 创建记录器，maxBodySize 大于0时记录不超过该大小的响应内容
+func NewRecorder(maxBodySize int) *Recorder {
+	return &Recorder{
+		maxBodySize: maxBodySize,
+		byID:        make(map[network.RequestID]*entry),
+	}
+}
+
+// OnEvent 处理 chromedp.ListenTarget 的事件
+func (r *Recorder) OnEvent(ev interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch ev := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		// 同一个 requestID 再次发送表示发生了重定向，上一跳以重定向响应结束
+		if prev, ok := r.byID[ev.RequestID]; ok && ev.RedirectResponse != nil {
+			prev.response = ev.RedirectResponse
+			prev.finished = true
+			prev.endTime = monotonic(ev.Timestamp)
+		}
+		e := &entry{
+			requestID: ev.RequestID,
+			request:   ev.Request,
+			startTime: monotonic(ev.Timestamp),
+		}
+		if ev.WallTime != nil {
+			e.wallTime = ev.WallTime.Time()
+		}
+		r.entries = append(r.entries, e)
+		r.byID[ev.RequestID] = e
+	case *network.EventResponseReceived:
+		if e, ok := r.byID[ev.RequestID]; ok {
+			e.response = ev.Response
+		}
+	case *network.EventLoadingFinished:
+		if e, ok := r.byID[ev.RequestID]; ok {
+			e.finished = true
+			e.endTime = monotonic(ev.Timestamp)
+			e.dataSize = ev.EncodedDataLength
+		}
+	case *network.EventLoadingFailed:
+		if e, ok := r.byID[ev.RequestID]; ok {
+			e.failed = ev.ErrorText
+			e.endTime = monotonic(ev.Timestamp)
+		}
+	case *page.EventDomContentEventFired:
+		if r.contentLoaded.IsZero() {
+			r.contentLoaded = monotonic(ev.Timestamp)
+		}
+	case *page.EventLoadEventFired:
+		if r.loaded.IsZero() {
+			r.loaded = monotonic(ev.Timestamp)
+		}
+	}
+}
+
+func monotonic(t *cdp.MonotonicTime) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return t.Time()
+}
+
+// FetchBodies 获取已完成请求的响应内容，需要在页面关闭前执行
+func (r *Recorder) FetchBodies() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if r.maxBodySize <= 0 {
+			return nil
+		}
+
+		r.mu.Lock()
+		var entries []*entry
+		for _, e := range r.entries {
+			// 重定向响应没有内容
+			if e.finished && e.failed == "" && e.response != nil && r.byID[e.requestID] == e {
+				entries = append(entries, e)
+			}
+		}
+		r.mu.Unlock()
+
+		for _, e := range entries {
+			body, comment := r.fetchBody(ctx, e)
+			r.mu.Lock()
+			e.body, e.comment = body, comment
+			r.mu.Unlock()
+		}
+		return nil
+	})
+}
+
+func (r *Recorder) fetchBody(ctx context.Context, e *entry) ([]byte, string) {
+	exceeded := fmt.Sprintf("body exceeds size limit of %d bytes", r.maxBodySize)
+
+	r.mu.Lock()
+	size := e.dataSize
+	r.mu.Unlock()
+	if size > float64(r.maxBodySize) {
+		return nil, exceeded
+	}
+
+	body, err := network.GetResponseBody(e.requestID).Do(ctx)
+	if err != nil {
+		// 部分资源（如已被缓存淘汰）无法获取内容，不影响整体结果
+		return nil, ""
+	}
+	if len(body) > r.maxBodySize {
+		return nil, exceeded
+	}
+	return body, ""
+}
+
+// HAR 生成 HAR 1.2 文档
+func (r *Recorder) HAR() *HAR {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	log := &Log{
+		Version: "1.2",
+		Creator: &Creator{Name: "chrome_proxy", Version: "1.0"},
+		Pages:   []*Page{},
+		Entries: []*Entry{},
+	}
+	if len(r.entries) == 0 {
+		return &HAR{Log: log}
+	}
+
+	first := r.entries[0]
+	log.Pages = append(log.Pages, &Page{
+		StartedDateTime: first.wallTime.Format(time.RFC3339Nano),
+		ID:              "page_1",
+		Title:           first.request.URL,
+		PageTimings: &PageTimings{
+			OnContentLoad: sinceMs(first.startTime, r.contentLoaded),
+			OnLoad:        sinceMs(first.startTime, r.loaded),
+		},
+	})
+
+	for _, e := range r.entries {
+		log.Entries = append(log.Entries, e.harEntry())
+	}
+	return &HAR{Log: log}
+}
+
+// sinceMs 返回 start 到 end 经过的毫秒数，end 未知时返回 -1
+func sinceMs(start, end time.Time) float64 {
+	if start.IsZero() || end.IsZero() {
+		return -1
+	}
+	return float64(end.Sub(start)) / float64(time.Millisecond)
+}
+
+func (e *entry) harEntry() *Entry {
+	he := &Entry{
+		Pageref:         "page_1",
+		StartedDateTime: e.wallTime.Format(time.RFC3339Nano),
+		Request:         e.harRequest(),
+		Response:        e.harResponse(),
+		Timings:         &Timings{Blocked: -1, DNS: -1, Connect: -1, Send: 0, Wait: 0, Receive: 0, SSL: -1},
+		Comment:         e.failed,
+	}
+	if e.comment != "" {
+		he.Response.Content.Comment = e.comment
+	}
+
+	if e.response != nil {
+		he.ServerIPAddress = e.response.RemoteIPAddress
+		if e.response.ConnectionID > 0 {
+			he.Connection = fmt.Sprintf("%.0f", e.response.ConnectionID)
+		}
+		if e.response.Timing != nil {
+			he.Timings = ConvertTimings(e.response.Timing, e.endTime)
+		}
+	}
+
+	he.Time = totalTime(he.Timings)
+	if he.Time == 0 && !e.endTime.IsZero() {
+		he.Time = sinceMs(e.startTime, e.endTime)
+	}
+	return he
+}
+
+func (e *entry) harRequest() *Request {
+	req := &Request{
+		Method:      e.request.Method,
+		URL:         e.request.URL + e.request.URLFragment,
+		HTTPVersion: "",
+		Cookies:     []NameValue{},
+		Headers:     headers(e.request.Headers),
+		QueryString: []NameValue{},
+		HeadersSize: -1,
+		BodySize:    0,
+	}
+	if e.response != nil {
+		req.HTTPVersion = httpVersion(e.response.Protocol)
+		if len(e.response.RequestHeaders) > 0 {
+			req.Headers = headers(e.response.RequestHeaders)
+		}
+	}
+	if u, err := url.Parse(e.request.URL); err == nil {
+		for k, vs := range u.Query() {
+			for _, v := range vs {
+				req.QueryString = append(req.QueryString, NameValue{Name: k, Value: v})
+			}
+		}
+		sort.Slice(req.QueryString, func(i, j int) bool {
+			return req.QueryString[i].Name < req.QueryString[j].Name
+		})
+	}
+	if e.request.HasPostData {
+		req.BodySize = int64(len(e.request.PostData))
+		req.PostData = &PostData{
+			MimeType: headerValue(e.request.Headers, "Content-Type"),
+			Text:     e.request.PostData,
+		}
+	}
+	return req
+}
+
+func (e *entry) harResponse() *Response {
+	resp := &Response{
+		Cookies:     []NameValue{},
+		Headers:     []NameValue{},
+		Content:     &Content{},
+		HeadersSize: -1,
+		BodySize:    -1,
+	}
+	if e.response == nil {
+		return resp
+	}
+
+	resp.Status = e.response.Status
+	resp.StatusText = e.response.StatusText
+	resp.HTTPVersion = httpVersion(e.response.Protocol)
+	resp.Headers = headers(e.response.Headers)
+	resp.RedirectURL = headerValue(e.response.Headers, "Location")
+	resp.Content.MimeType = e.response.MimeType
+	if e.finished {
+		resp.BodySize = int64(e.dataSize)
+	}
+
+	if e.body != nil {
+		resp.Content.Size = int64(len(e.body))
+		if isText(e.response.MimeType) && utf8.Valid(e.body) {
+			resp.Content.Text = string(e.body)
+		} else {
+			resp.Content.Text = base64.StdEncoding.EncodeToString(e.body)
+			resp.Content.Encoding = "base64"
+		}
+	}
+	return resp
+}
+
+// ConvertTimings 将 chrome 的 ResourceTiming 转换为 HAR 的 timings
+// chrome 中各阶段均为相对于 RequestTime 的毫秒数，-1 表示没有该阶段
+func ConvertTimings(t *network.ResourceTiming, endTime time.Time) *Timings {
+	timings := &Timings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1}
+
+	// 第一个阶段开始之前都算作阻塞时间
+	blocked := t.SendStart
+	for _, start := range []float64{t.ConnectStart, t.DNSStart} {
+		if start >= 0 {
+			blocked = start
+		}
+	}
+	timings.Blocked = round(blocked)
+
+	if t.DNSStart >= 0 {
+		timings.DNS = round(t.DNSEnd - t.DNSStart)
+	}
+	if t.ConnectStart >= 0 {
+		timings.Connect = round(t.ConnectEnd - t.ConnectStart)
+	}
+	if t.SslStart >= 0 {
+		timings.SSL = round(t.SslEnd - t.SslStart)
+	}
+	timings.Send = round(t.SendEnd - t.SendStart)
+	timings.Wait = round(t.ReceiveHeadersEnd - t.SendEnd)
+
+	if !endTime.IsZero() {
+		headersEnd := cdp.MonotonicTimeEpoch.Add(time.Duration((t.RequestTime*1000 + t.ReceiveHeadersEnd) * float64(time.Millisecond)))
+		if receive := float64(endTime.Sub(headersEnd)) / float64(time.Millisecond); receive > 0 {
+			timings.Receive = round(receive)
+		}
+	}
+	return timings
+}
+
+// totalTime 各阶段耗时之和，ssl 已经包含在 connect 中
+func totalTime(t *Timings) float64 {
+	var total float64
+	for _, v := range []float64{t.Blocked, t.DNS, t.Connect, t.Send, t.Wait, t.Receive} {
+		if v > 0 {
+			total += v
+		}
+	}
+	return round(total)
+}
+
+func round(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
+
+func httpVersion(protocol string) string {
+	switch strings.ToLower(protocol) {
+	case "":
+		return ""
+	case "h2":
+		return "HTTP/2"
+	case "h3":
+		return "HTTP/3"
+	}
+	return strings.ToUpper(protocol)
+}
+
+// headers 转换为按名称排序的键值对，chrome 中多个同名 header 以换行分隔
+func headers(h network.Headers) []NameValue {
+	result := []NameValue{}
+	for k, v := range h {
+		for _, line := range strings.Split(fmt.Sprint(v), "\n") {
+			result = append(result, NameValue{Name: k, Value: line})
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+func headerValue(h network.Headers, name string) string {
+	for k, v := range h {
+		if strings.EqualFold(k, name) {
+			return fmt.Sprint(v)
+		}
+	}
+	return ""
+}
+
+func isText(mimeType string) bool {
+	mimeType = strings.ToLower(mimeType)
+	if strings.HasPrefix(mimeType, "text/") {
+		return true
+	}
+	for _, s := range []string{"json", "javascript", "xml", "svg"} {
+		if strings.Contains(mimeType, s) {
+			return true
+		}
+	}
+	return false
+}
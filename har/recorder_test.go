@@ -0,0 +1,135 @@
+package har
+
+import (
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func monotonicAt(ms float64) *cdp.MonotonicTime {
+	t := cdp.MonotonicTime(cdp.MonotonicTimeEpoch.Add(time.Duration(ms * float64(time.Millisecond))))
+	return &t
+}
+
+func TestConvertTimings(t *testing.T) {
+	tests := []struct {
+		name    string
+		timing  *network.ResourceTiming
+		endTime time.Time
+		want    *Timings
+	}{
+		{
+			name: "测试新建https连接",
+			timing: &network.ResourceTiming{
+				RequestTime:       1,
+				DNSStart:          1,
+				DNSEnd:            3,
+				ConnectStart:      3,
+				ConnectEnd:        10,
+				SslStart:          5,
+				SslEnd:            10,
+				SendStart:         10,
+				SendEnd:           11,
+				ReceiveHeadersEnd: 30,
+			},
+			endTime: monotonicAt(1000 + 40).Time(),
+			want:    &Timings{Blocked: 1, DNS: 2, Connect: 7, SSL: 5, Send: 1, Wait: 19, Receive: 10},
+		},
+		{
+			name: "测试复用连接",
+			timing: &network.ResourceTiming{
+				RequestTime:       1,
+				DNSStart:          -1,
+				DNSEnd:            -1,
+				ConnectStart:      -1,
+				ConnectEnd:        -1,
+				SslStart:          -1,
+				SslEnd:            -1,
+				SendStart:         2,
+				SendEnd:           2.5,
+				ReceiveHeadersEnd: 20,
+			},
+			want: &Timings{Blocked: 2, DNS: -1, Connect: -1, SSL: -1, Send: 0.5, Wait: 17.5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ConvertTimings(tt.timing, tt.endTime))
+		})
+	}
+}
+
+func TestRecorder_HAR(t *testing.T) {
+	wall := cdp.TimeSinceEpoch(time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC))
+	redirect := &network.Response{
+		URL:        "http://example.com/",
+		Status:     301,
+		StatusText: "Moved Permanently",
+		Headers:    network.Headers{"Location": "https://example.com/?a=1"},
+		Protocol:   "http/1.1",
+	}
+
+	r := NewRecorder(0)
+	r.OnEvent(&network.EventRequestWillBeSent{
+		RequestID: "1",
+		Request:   &network.Request{URL: "http://example.com/", Method: "GET", Headers: network.Headers{}},
+		Timestamp: monotonicAt(0),
+		WallTime:  &wall,
+	})
+	r.OnEvent(&network.EventRequestWillBeSent{
+		RequestID:        "1",
+		Request:          &network.Request{URL: "https://example.com/?a=1", Method: "GET", Headers: network.Headers{}},
+		RedirectResponse: redirect,
+		Timestamp:        monotonicAt(50),
+		WallTime:         &wall,
+	})
+	r.OnEvent(&network.EventResponseReceived{
+		RequestID: "1",
+		Response: &network.Response{
+			URL:             "https://example.com/?a=1",
+			Status:          200,
+			StatusText:      "OK",
+			Headers:         network.Headers{"Content-Type": "text/html", "Set-Cookie": "a=1\nb=2"},
+			MimeType:        "text/html",
+			Protocol:        "h2",
+			RemoteIPAddress: "93.184.216.34",
+		},
+	})
+	r.OnEvent(&network.EventLoadingFinished{RequestID: "1", Timestamp: monotonicAt(120), EncodedDataLength: 1256})
+	r.OnEvent(&network.EventRequestWillBeSent{
+		RequestID: "2",
+		Request:   &network.Request{URL: "https://example.com/x.js", Method: "GET", Headers: network.Headers{}},
+		Timestamp: monotonicAt(130),
+		WallTime:  &wall,
+	})
+	r.OnEvent(&network.EventLoadingFailed{RequestID: "2", Timestamp: monotonicAt(140), ErrorText: "net::ERR_NAME_NOT_RESOLVED"})
+
+	h := r.HAR()
+	assert.Equal(t, "1.2", h.Log.Version)
+	assert.Len(t, h.Log.Pages, 1)
+	assert.Len(t, h.Log.Entries, 3)
+
+	e := h.Log.Entries[0]
+	assert.Equal(t, int64(301), e.Response.Status)
+	assert.Equal(t, "https://example.com/?a=1", e.Response.RedirectURL)
+	assert.Equal(t, "HTTP/1.1", e.Response.HTTPVersion)
+	assert.Equal(t, float64(50), e.Time)
+
+	e = h.Log.Entries[1]
+	assert.Equal(t, int64(200), e.Response.Status)
+	assert.Equal(t, "HTTP/2", e.Request.HTTPVersion)
+	assert.Equal(t, []NameValue{{Name: "a", Value: "1"}}, e.Request.QueryString)
+	assert.Equal(t, []NameValue{
+		{Name: "Content-Type", Value: "text/html"},
+		{Name: "Set-Cookie", Value: "a=1"},
+		{Name: "Set-Cookie", Value: "b=2"},
+	}, e.Response.Headers)
+	assert.Equal(t, int64(1256), e.Response.BodySize)
+	assert.Equal(t, "93.184.216.34", e.ServerIPAddress)
+
+	e = h.Log.Entries[2]
+	assert.Equal(t, "net::ERR_NAME_NOT_RESOLVED", e.Comment)
+	assert.Equal(t, int64(0), e.Response.Status)
+}
@@ -0,0 +1,223 @@
+package job
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/LubyRuffy/chrome_proxy/limiter"
+	"github.com/LubyRuffy/chrome_proxy/models"
+	"github.com/LubyRuffy/chrome_proxy/task"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Status 任务状态
+type Status string
+
+const (
+	StatusQueued   Status = "queued"
+	StatusRunning  Status = "running"
+	StatusDone     Status = "done"
+	StatusFailed   Status = "failed"
+	StatusCanceled Status = "canceled"
+)
+
+var (
+	// ErrFinished 任务已结束，无法取消
+	ErrFinished = errors.New("job already finished")
+	// ErrQueueFull 排队的任务数已达上限
+	ErrQueueFull = errors.New("too many queued jobs")
+)
+
+// Job 异步任务
+type Job struct {
+	ID        string         `json:"id"`
+	Action    string         `json:"action"`
+	Url       string         `json:"url"`
+	Status    Status         `json:"status"`
+	Result    *models.Result `json:"result,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// Param 异步任务参数
+type Param struct {
+	models.ChromeParam
+	Action string `json:"action"`
+}
+
+// Response 任务接口输出结果
+type Response struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+	Job     *Job   `json:"job,omitempty"`
+}
+
+func (r Response) Bytes() []byte {
+	d, _ := json.Marshal(r)
+	return d
+}
+
+// GetParamFromRequest 从请求中读取任务参数
+func GetParamFromRequest(r *http.Request) (*Param, error) {
+	var p Param
+	err := json.NewDecoder(r.Body).Decode(&p)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+
+	if p.Action == "" {
+		p.Action = models.ActionScreenshot
+	}
+	if !task.Valid(p.Action) {
+		return nil, errors.New("unknown action: " + p.Action)
+	}
+	if p.Timeout == 0 {
+		p.Timeout = 20
+	}
+	return &p, nil
+}
+
+// Manager 异步任务管理，负责执行、查询和取消任务
+// 任务使用独立的等待队列，不受 http 请求的排队数和排队超时限制
+type Manager struct {
+	store Store
+	l     *limiter.Limiter
+	queue chan struct{}
+
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+}
+
+// NewManager 创建任务管理器，任务执行前需要从 l 获取执行槽位
+// queueSize 为最多排队的任务数，超过后提交任务失败
+func NewManager(store Store, l *limiter.Limiter, queueSize int) *Manager {
+	if queueSize < 0 {
+		queueSize = 0
+	}
+	return &Manager{
+		store:   store,
+		l:       l,
+		queue:   make(chan struct{}, queueSize),
+		cancels: make(map[string]context.CancelFunc),
+	}
+}
+
+// Waiting 正在排队的任务数
+func (m *Manager) Waiting() int {
+	return len(m.queue)
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Submit 提交任务并立即返回，任务在后台执行，排队的任务数已满时返回 ErrQueueFull
+func (m *Manager) Submit(p *Param) (*Job, error) {
+	select {
+	case m.queue <- struct{}{}:
+	default:
+		return nil, ErrQueueFull
+	}
+
+	now := time.Now()
+	job := &Job{
+		ID:        newID(),
+		Action:    p.Action,
+		Url:       p.URL,
+		Status:    StatusQueued,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := m.store.Save(job); err != nil {
+		<-m.queue
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.mu.Lock()
+	m.cancels[job.ID] = cancel
+	m.mu.Unlock()
+
+	j := *job
+	go m.run(ctx, &j, p)
+	return job, nil
+}
+
+func (m *Manager) run(ctx context.Context, job *Job, p *Param) {
+	var result models.Result
+	err := m.l.Wait(ctx)
+	<-m.queue
+	if err != nil {
+		// 只有取消时才会等待失败
+		result = models.Result{Code: 500, Url: p.URL, Message: err.Error(), Action: p.Action}
+	} else {
+		// 持锁更新，避免覆盖 Cancel 写入的状态
+		m.mu.Lock()
+		if _, ok := m.cancels[job.ID]; ok {
+			m.update(job, StatusRunning, nil)
+		}
+		m.mu.Unlock()
+
+		result = task.Run(ctx, p.Action, &p.ChromeParam)
+		m.l.Release()
+	}
+
+	m.mu.Lock()
+	cancel, ok := m.cancels[job.ID]
+	delete(m.cancels, job.ID)
+	m.mu.Unlock()
+
+	// 被取消的任务状态已经在 Cancel 中更新
+	if !ok {
+		return
+	}
+	cancel()
+
+	if result.Code == 200 {
+		m.update(job, StatusDone, &result)
+	} else {
+		m.update(job, StatusFailed, &result)
+	}
+}
+
+func (m *Manager) update(job *Job, status Status, result *models.Result) {
+	job.Status = status
+	job.Result = result
+	job.UpdatedAt = time.Now()
+	if err := m.store.Save(job); err != nil {
+		log.Println("[WARNING] save job failed:", err)
+	}
+}
+
+// Get 查询任务
+func (m *Manager) Get(id string) (*Job, error) {
+	return m.store.Get(id)
+}
+
+// Cancel 取消排队中或者执行中的任务
+func (m *Manager) Cancel(id string) (*Job, error) {
+	job, err := m.store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cancel, ok := m.cancels[id]
+	if !ok {
+		return job, ErrFinished
+	}
+	delete(m.cancels, id)
+
+	cancel()
+	m.update(job, StatusCanceled, nil)
+	return job, nil
+}
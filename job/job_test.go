@@ -0,0 +1,117 @@
+package job
+
+import (
+	"context"
+	"github.com/LubyRuffy/chrome_proxy/limiter"
+	"github.com/LubyRuffy/chrome_proxy/models"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetParamFromRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    *Param
+		wantErr bool
+	}{
+		{
+			name: "测试默认参数",
+			body: `{"url":"https://fofa.info"}`,
+			want: &Param{
+				ChromeParam: models.ChromeParam{ChromeActionInput: models.ChromeActionInput{URL: "https://fofa.info", Timeout: 20}},
+				Action:      models.ActionScreenshot,
+			},
+		},
+		{
+			name: "测试dom任务",
+			body: `{"url":"https://fofa.info","sleep":30,"timeout":60,"action":"dom"}`,
+			want: &Param{
+				ChromeParam: models.ChromeParam{ChromeActionInput: models.ChromeActionInput{URL: "https://fofa.info", Sleep: 30, Timeout: 60}},
+				Action:      models.ActionDom,
+			},
+		},
+		{
+			name:    "测试未知动作",
+			body:    `{"url":"https://fofa.info","action":"unknown"}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetParamFromRequest(httptest.NewRequest("POST", "/jobs", strings.NewReader(tt.body)))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestManager_Cancel(t *testing.T) {
+	// 占满唯一的执行槽位，让任务停留在排队状态
+	l := limiter.New(1, 1, 0)
+	assert.Nil(t, l.Acquire(context.Background()))
+	defer l.Release()
+
+	m := NewManager(NewMemoryStore(time.Minute), l, 10)
+	j, err := m.Submit(&Param{
+		ChromeParam: models.ChromeParam{ChromeActionInput: models.ChromeActionInput{URL: "https://fofa.info"}},
+		Action:      models.ActionScreenshot,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, StatusQueued, j.Status)
+
+	got, err := m.Cancel(j.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, StatusCanceled, got.Status)
+
+	// 排队中的任务退出后状态保持为已取消
+	for m.Waiting() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	got, err = m.Get(j.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, StatusCanceled, got.Status)
+
+	_, err = m.Cancel(j.ID)
+	assert.Equal(t, ErrFinished, err)
+
+	_, err = m.Get("not-exists")
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestManager_Submit_queue(t *testing.T) {
+	// http 请求的队列为0且很快超时，任务不受影响
+	l := limiter.New(1, 0, 10*time.Millisecond)
+	assert.Nil(t, l.Acquire(context.Background()))
+	defer l.Release()
+
+	m := NewManager(NewMemoryStore(time.Minute), l, 1)
+	p := &Param{
+		ChromeParam: models.ChromeParam{ChromeActionInput: models.ChromeActionInput{URL: "https://fofa.info"}},
+		Action:      models.ActionScreenshot,
+	}
+	j, err := m.Submit(p)
+	assert.Nil(t, err)
+
+	// 任务队列已满时直接拒绝
+	_, err = m.Submit(p)
+	assert.Equal(t, ErrQueueFull, err)
+
+	time.Sleep(50 * time.Millisecond)
+	got, err := m.Get(j.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, StatusQueued, got.Status)
+
+	_, err = m.Cancel(j.ID)
+	assert.Nil(t, err)
+	for m.Waiting() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,86 @@
+package job
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNotFound 任务不存在或已过期
+var ErrNotFound = errors.New("job not found")
+
+// Store 任务存储接口，可以替换为持久化实现
+type Store interface {
+	// Save 保存（新建或更新）任务
+	Save(job *Job) error
+	// Get 获取任务，不存在时返回 ErrNotFound
+	Get(id string) (*Job, error)
+	// Delete 删除任务
+	Delete(id string) error
+}
+
+type memoryEntry struct {
+	job      Job
+	expireAt time.Time
+}
+
+// MemoryStore 内存任务存储，任务在最后一次更新 ttl 之后过期
+type MemoryStore struct {
+	ttl time.Duration
+
+	mu   sync.Mutex
+	jobs map[string]*memoryEntry
+}
+
+// NewMemoryStore 创建内存任务存储
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{
+		ttl:  ttl,
+		jobs: make(map[string]*memoryEntry),
+	}
+}
+
+// Save 保存任务副本，并顺带清理过期任务
+func (s *MemoryStore) Save(job *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for id, e := range s.jobs {
+		if now.After(e.expireAt) {
+			delete(s.jobs, id)
+		}
+	}
+
+	s.jobs[job.ID] = &memoryEntry{
+		job:      *job,
+		expireAt: now.Add(s.ttl),
+	}
+	return nil
+}
+
+// Get 返回任务副本
+func (s *MemoryStore) Get(id string) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if time.Now().After(e.expireAt) {
+		delete(s.jobs, id)
+		return nil, ErrNotFound
+	}
+	job := e.job
+	return &job, nil
+}
+
+// Delete 删除任务
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.jobs, id)
+	return nil
+}
@@ -0,0 +1,35 @@
+package job
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	s := NewMemoryStore(50 * time.Millisecond)
+
+	job := &Job{ID: "1", Status: StatusQueued}
+	assert.Nil(t, s.Save(job))
+
+	// 返回的是副本，修改不影响存储
+	got, err := s.Get("1")
+	assert.Nil(t, err)
+	got.Status = StatusDone
+	got, err = s.Get("1")
+	assert.Nil(t, err)
+	assert.Equal(t, StatusQueued, got.Status)
+
+	_, err = s.Get("2")
+	assert.Equal(t, ErrNotFound, err)
+
+	// 过期
+	time.Sleep(60 * time.Millisecond)
+	_, err = s.Get("1")
+	assert.Equal(t, ErrNotFound, err)
+
+	assert.Nil(t, s.Save(job))
+	assert.Nil(t, s.Delete("1"))
+	_, err = s.Get("1")
+	assert.Equal(t, ErrNotFound, err)
+}
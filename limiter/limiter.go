@@ -0,0 +1,102 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrQueueFull 等待队列已满
+	ErrQueueFull = errors.New("server is busy: queue is full")
+	// ErrQueueTimeout 排队超时
+	ErrQueueTimeout = errors.New("server is busy: queue timeout")
+)
+
+// Limiter 并发限制器，超过并发数的请求进入有界队列等待
+type Limiter struct {
+	slots        chan struct{}
+	queue        chan struct{}
+	queueTimeout time.Duration
+}
+
+// New 创建并发限制器
+// maxConcurrency 最大并发数，queueSize 最大排队数，queueTimeout 排队超时时间（0表示不超时）
+func New(maxConcurrency, queueSize int, queueTimeout time.Duration) *Limiter {
+	if maxConcurrency <= 0 {
+		maxConcurrency = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+	return &Limiter{
+		slots:        make(chan struct{}, maxConcurrency),
+		queue:        make(chan struct{}, queueSize),
+		queueTimeout: queueTimeout,
+	}
+}
+
+// Acquire 获取一个执行槽位，成功后必须调用 Release 归还
+func (l *Limiter) Acquire(ctx context.Context) error {
+	// 有空闲槽位直接执行
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	// 进入等待队列
+	select {
+	case l.queue <- struct{}{}:
+	default:
+		return ErrQueueFull
+	}
+	defer func() { <-l.queue }()
+
+	var timeout <-chan time.Time
+	if l.queueTimeout > 0 {
+		timer := time.NewTimer(l.queueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-timeout:
+		return ErrQueueTimeout
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Wait 等待执行槽位直到 ctx 取消，不占用等待队列也不超时，用于自带队列的异步任务
+// 成功后必须调用 Release 归还
+func (l *Limiter) Wait(ctx context.Context) error {
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release 归还执行槽位
+func (l *Limiter) Release() {
+	<-l.slots
+}
+
+// Cap 最大并发数
+func (l *Limiter) Cap() int {
+	return cap(l.slots)
+}
+
+// Running 正在执行的数量
+func (l *Limiter) Running() int {
+	return len(l.slots)
+}
+
+// Waiting 正在排队的数量
+func (l *Limiter) Waiting() int {
+	return len(l.queue)
+}
@@ -0,0 +1,70 @@
+package limiter
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestLimiter_Acquire(t *testing.T) {
+	tests := []struct {
+		name           string
+		maxConcurrency int
+		queueSize      int
+		queueTimeout   time.Duration
+		running        int
+		wantErr        error
+	}{
+		{
+			name:           "测试有空闲槽位",
+			maxConcurrency: 2,
+			queueSize:      1,
+			running:        1,
+			wantErr:        nil,
+		},
+		{
+			name:           "测试队列已满",
+			maxConcurrency: 1,
+			queueSize:      0,
+			running:        1,
+			wantErr:        ErrQueueFull,
+		},
+		{
+			name:           "测试排队超时",
+			maxConcurrency: 1,
+			queueSize:      1,
+			queueTimeout:   50 * time.Millisecond,
+			running:        1,
+			wantErr:        ErrQueueTimeout,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(tt.maxConcurrency, tt.queueSize, tt.queueTimeout)
+			for i := 0; i < tt.running; i++ {
+				assert.Nil(t, l.Acquire(context.Background()))
+			}
+			assert.Equal(t, tt.wantErr, l.Acquire(context.Background()))
+			assert.Equal(t, 0, l.Waiting())
+		})
+	}
+}
+
+func TestLimiter_Release(t *testing.T) {
+	l := New(1, 1, time.Second)
+	assert.Nil(t, l.Acquire(context.Background()))
+
+	done := make(chan error)
+	go func() {
+		done <- l.Acquire(context.Background())
+	}()
+
+	// 等待第二个请求进入队列后释放槽位
+	for l.Waiting() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	l.Release()
+	assert.Nil(t, <-done)
+	assert.Equal(t, 1, l.Running())
+}
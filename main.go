@@ -2,77 +2,214 @@ package main
 
 import (
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"flag"
+	"github.com/LubyRuffy/chrome_proxy/batch"
+	"github.com/LubyRuffy/chrome_proxy/browser_pool"
+	"github.com/LubyRuffy/chrome_proxy/callback"
+	"github.com/LubyRuffy/chrome_proxy/chrome_action"
+	"github.com/LubyRuffy/chrome_proxy/job"
+	"github.com/LubyRuffy/chrome_proxy/limiter"
 	"github.com/LubyRuffy/chrome_proxy/models"
-	"github.com/LubyRuffy/chrome_proxy/render_dom"
-	"github.com/LubyRuffy/chrome_proxy/screenshot"
+	"github.com/LubyRuffy/chrome_proxy/proxy_pool"
+	"github.com/LubyRuffy/chrome_proxy/task"
 	"github.com/LubyRuffy/chrome_proxy/utils"
 	"log"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 )
 
 func main() {
 	addr := flag.String("addr", ":5558", "http server listen address")
+	poolSize := flag.Int("pool-size", 0, "number of warm browsers kept in pool, 0 means launch chrome per request")
+	poolMaxUses := flag.Int("pool-max-uses", 100, "recycle a pooled browser after serving this many requests, 0 means unlimited")
+	maxConcurrency := flag.Int("max-concurrency", 4, "max number of renders running at the same time")
+	queueSize := flag.Int("queue-size", 100, "max number of requests waiting for a free render slot")
+	queueTimeout := flag.Duration("queue-timeout", 30*time.Second, "max time a request waits in queue, 0 means no timeout")
+	jobTTL := flag.Duration("job-ttl", time.Hour, "how long async job results are kept")
+	jobQueueSize := flag.Int("job-queue-size", 1000, "max number of async jobs waiting for a free render slot")
+	callbackSecret := flag.String("callback-secret", "", "secret used to sign callback requests with HMAC-SHA256")
+	callbackRetries := flag.Int("callback-retries", 5, "max retries when a callback request fails")
+	proxyPool := flag.String("proxy-pool", "", "proxy pool config file (json), used when request proxy is \"pool\"")
 	flag.Parse()
 
-	http.HandleFunc("/screenshot", func(w http.ResponseWriter, r *http.Request) {
+	callback.DefaultSender.Secret = *callbackSecret
+	callback.DefaultSender.MaxRetries = *callbackRetries
+
+	l := limiter.New(*maxConcurrency, *queueSize, *queueTimeout)
+	jobs := job.NewManager(job.NewMemoryStore(*jobTTL), l, *jobQueueSize)
+
+	if *poolSize > 0 {
+		pool, err := browser_pool.New(*poolSize, *poolMaxUses,
+			chrome_action.ExecAllocatorOptions(models.ChromeActionInput{})...)
+		if err != nil {
+			log.Fatal("create browser pool failed: ", err)
+		}
+		defer pool.Close()
+		chrome_action.Pool = pool
+		log.Println("browser pool started, size:", *poolSize)
+	}
+
+	if *proxyPool != "" {
+		pool, err := proxy_pool.Load(*proxyPool)
+		if err != nil {
+			log.Fatal("load proxy pool failed: ", err)
+		}
+		defer pool.Close()
+		chrome_action.ProxyPool = pool
+		log.Println("proxy pool loaded, size:", len(pool.Stats()))
+	}
+
+	http.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
-		options, err := utils.GetOptionFromRequest(r)
-		if options == nil {
-			w.Write(models.Result{
+		var stats browser_pool.Stats
+		if chrome_action.Pool != nil {
+			stats = chrome_action.Pool.Stats()
+		}
+		var proxies []proxy_pool.Stat
+		if chrome_action.ProxyPool != nil {
+			proxies = chrome_action.ProxyPool.Stats()
+		}
+		json.NewEncoder(w).Encode(struct {
+			browser_pool.Stats
+			Running int               `json:"running"`
+			Waiting int               `json:"waiting"`
+			Proxies []proxy_pool.Stat `json:"proxies,omitempty"`
+		}{stats, l.Running(), l.Waiting(), proxies})
+	})
+
+	http.HandleFunc("/screenshot", limitHandler(l, rawHandler(models.ActionScreenshot)))
+	http.HandleFunc("/renderDom", limitHandler(l, actionHandler(models.ActionDom)))
+	http.HandleFunc("/pdf", limitHandler(l, rawHandler(models.ActionPDF)))
+	http.HandleFunc("/archive", limitHandler(l, actionHandler(models.ActionArchive)))
+	http.HandleFunc("/extract", limitHandler(l, actionHandler(models.ActionExtract)))
+
+	// 批量任务不占用限制器槽位，其中每个目标单独排队
+	http.HandleFunc("/batch", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		param, err := batch.GetParamFromRequest(r)
+		if err != nil {
+			w.Write(models.BatchResult{
 				Code:    500,
 				Message: err.Error(),
 			}.Bytes())
 			return
 		}
 
-		screenshotResult, err := screenshot.ScreenshotURL(options)
+		options, err := param.Options()
 		if err != nil {
-			w.Write(models.Result{
+			w.Write(models.BatchResult{
 				Code:    500,
 				Message: err.Error(),
 			}.Bytes())
 			return
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.Write(models.Result{
-			Code:     200,
-			Url:      options.URL,
-			Data:     base64.StdEncoding.EncodeToString(screenshotResult.Data),
-			Title:    screenshotResult.Title,
-			Location: screenshotResult.Location,
+		w.Write(models.BatchResult{
+			Code:    200,
+			Results: batch.Run(r.Context(), l, options, param.Actions),
 		}.Bytes())
 	})
 
-	http.HandleFunc("/renderDom", func(w http.ResponseWriter, r *http.Request) {
+	// 提交异步任务
+	http.HandleFunc("/jobs", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
-		options, err := utils.GetOptionFromRequest(r)
-		if options == nil {
-			w.Write(models.Result{
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			w.Write(job.Response{
+				Code:    http.StatusMethodNotAllowed,
+				Message: "method not allowed",
+			}.Bytes())
+			return
+		}
+
+		param, err := job.GetParamFromRequest(r)
+		if err != nil {
+			w.Write(job.Response{
 				Code:    500,
 				Message: err.Error(),
 			}.Bytes())
 			return
 		}
 
-		data, err := render_dom.RenderDom(options)
+		j, err := jobs.Submit(param)
+		if errors.Is(err, job.ErrQueueFull) {
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write(job.Response{
+				Code:    http.StatusTooManyRequests,
+				Message: err.Error(),
+			}.Bytes())
+			return
+		}
 		if err != nil {
-			w.Write(models.Result{
+			w.Write(job.Response{
 				Code:    500,
 				Message: err.Error(),
 			}.Bytes())
 			return
 		}
+		w.Write(job.Response{
+			Code: 200,
+			Job:  j,
+		}.Bytes())
+	})
+
+	// 查询（GET）或者取消（DELETE）异步任务
+	http.HandleFunc("/jobs/", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
-		w.Write(models.Result{
-			Code:     200,
-			Url:      options.URL,
-			Data:     data.Html,
-			Title:    data.Title,
-			Location: data.Location,
+
+		id := strings.TrimPrefix(r.URL.Path, "/jobs/")
+
+		var j *job.Job
+		var err error
+		switch r.Method {
+		case http.MethodGet:
+			j, err = jobs.Get(id)
+		case http.MethodDelete:
+			j, err = jobs.Cancel(id)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			w.Write(job.Response{
+				Code:    http.StatusMethodNotAllowed,
+				Message: "method not allowed",
+			}.Bytes())
+			return
+		}
+
+		if errors.Is(err, job.ErrNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write(job.Response{
+				Code:    http.StatusNotFound,
+				Message: err.Error(),
+			}.Bytes())
+			return
+		}
+		if errors.Is(err, job.ErrFinished) {
+			w.WriteHeader(http.StatusConflict)
+			w.Write(job.Response{
+				Code:    http.StatusConflict,
+				Message: err.Error(),
+				Job:     j,
+			}.Bytes())
+			return
+		}
+		if err != nil {
+			w.Write(job.Response{
+				Code:    500,
+				Message: err.Error(),
+				Job:     j,
+			}.Bytes())
+			return
+		}
+		w.Write(job.Response{
+			Code: 200,
+			Job:  j,
 		}.Bytes())
 	})
 
@@ -82,3 +219,115 @@ func main() {
 		log.Fatal("ListenAndServe: ", err)
 	}
 }
+
+// actionHandler 执行单个渲染动作
+func actionHandler(action string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		options, err := utils.GetOptionFromRequest(r)
+		if options == nil {
+			w.Write(models.Result{
+				Code:    500,
+				Message: err.Error(),
+			}.Bytes())
+			return
+		}
+
+		w.Write(task.Run(r.Context(), action, options).Bytes())
+	}
+}
+
+// rawHandler 截图或者导出pdf，默认返回 json
+// 请求 raw=1 或者 Accept 为图片、pdf类型时直接返回文件内容
+func rawHandler(action string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		formats, raw := utils.RawFormats(r)
+		if !raw {
+			actionHandler(action)(w, r)
+			return
+		}
+		writeRaw(w, r, action, formats)
+	}
+}
+
+// writeRaw 执行渲染动作并直接返回文件内容，标题和地址放在 header 中
+func writeRaw(w http.ResponseWriter, r *http.Request, action string, formats []string) {
+	options, err := utils.GetOptionFromRequest(r)
+	if options == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(models.Result{
+			Code:    500,
+			Message: err.Error(),
+		}.Bytes())
+		return
+	}
+
+	// 截图时参数中的 format 也必须是 Accept 可以接受的格式
+	var want string
+	if action == models.ActionScreenshot {
+		want = options.Format
+	}
+	format, ok := utils.NegotiateFormat(action, formats, want)
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotAcceptable)
+		w.Write(models.Result{
+			Code:    http.StatusNotAcceptable,
+			Message: "not acceptable: " + r.Header.Get("Accept"),
+		}.Bytes())
+		return
+	}
+	if action == models.ActionScreenshot {
+		options.Format = format
+	}
+
+	result := task.Run(r.Context(), action, options)
+	if result.Code != 200 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(result.Code)
+		w.Write(result.Bytes())
+		return
+	}
+
+	// task.Run 统一输出base64，这里解码后直接返回
+	data, err := base64.StdEncoding.DecodeString(result.Data)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(models.Result{
+			Code:    500,
+			Message: err.Error(),
+		}.Bytes())
+		return
+	}
+
+	if result.Format == models.FormatPDF {
+		w.Header().Set("Content-Type", "application/pdf")
+	} else {
+		w.Header().Set("Content-Type", "image/"+result.Format)
+	}
+	w.Header().Set("X-Url", result.Url)
+	w.Header().Set("X-Title", url.PathEscape(result.Title))
+	w.Header().Set("X-Location", result.Location)
+	w.Write(data)
+}
+
+// limitHandler 限制同时渲染的数量，排队已满或排队超时时返回 429
+func limitHandler(l *limiter.Limiter, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := l.Acquire(r.Context()); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write(models.Result{
+				Code:    http.StatusTooManyRequests,
+				Message: err.Error(),
+			}.Bytes())
+			return
+		}
+		defer l.Release()
+
+		h(w, r)
+	}
+}
@@ -0,0 +1,126 @@
+package pdf
+
+import (
+	"context"
+	"fmt"
+	"github.com/LubyRuffy/chrome_proxy/chrome_action"
+	"github.com/LubyRuffy/chrome_proxy/models"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+	"log"
+	"strings"
+)
+
+// paperSizes 常用纸张大小（英寸），竖向
+var paperSizes = map[string][2]float64{
+	"letter":  {8.5, 11},
+	"legal":   {8.5, 14},
+	"tabloid": {11, 17},
+	"a3":      {11.69, 16.54},
+	"a4":      {8.27, 11.69},
+	"a5":      {5.83, 8.27},
+}
+
+// PrintPDF 将页面打印为pdf
+func PrintPDF(options *models.ChromeParam) (*models.PDFOutput, error) {
+	return PrintPDFContext(context.Background(), options)
+}
+
+// PrintPDFContext 将页面打印为pdf，ctx 取消时中止
+func PrintPDFContext(ctx context.Context, options *models.ChromeParam) (*models.PDFOutput, error) {
+	log.Println("PrintPDF of url:", options.URL)
+
+	actions, finish, err := Actions(options)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := chrome_action.ChromeActionsContext(ctx, options.ChromeActionInput, func(s string, i ...interface{}) {
+
+	}, options.Timeout, nil, actions...)
+	if err != nil {
+		return nil, fmt.Errorf("PrintPDF failed(%w): %s", err, options.URL)
+	}
+	return finish(out)
+}
+
+// Actions 生成导出pdf需要在页面中执行的动作，渲染完成后调用 finish 生成结果
+// 用于与其他动作共享同一次渲染
+func Actions(options *models.ChromeParam) ([]chromedp.Action, func(out *models.ChromeActionOutput) (*models.PDFOutput, error), error) {
+	params, err := printParams(options.PDF)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var buf []byte
+	var actions []chromedp.Action
+	actions = append(actions, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		buf, _, err = params.Do(ctx)
+		return err
+	}))
+
+	var title string
+	actions = append(actions, chromedp.Title(&title))
+	var location string
+	actions = append(actions, chromedp.Location(&location))
+
+	return actions, func(out *models.ChromeActionOutput) (*models.PDFOutput, error) {
+		return &models.PDFOutput{
+			Data:     buf,
+			Title:    title,
+			Location: location,
+
+			ChromeActionOutput: *out,
+		}, nil
+	}, nil
+}
+
+// printParams 生成 Page.printToPDF 的参数
+func printParams(o models.PDFOptions) (*page.PrintToPDFParams, error) {
+	params := page.PrintToPDF().
+		WithLandscape(o.Landscape).
+		WithPrintBackground(o.PrintBackground)
+
+	if o.PaperSize != "" {
+		size, ok := paperSizes[strings.ToLower(o.PaperSize)]
+		if !ok {
+			return nil, fmt.Errorf("unknown paper size: %s", o.PaperSize)
+		}
+		params = params.WithPaperWidth(size[0]).WithPaperHeight(size[1])
+	}
+	if o.PaperWidth > 0 {
+		params = params.WithPaperWidth(o.PaperWidth)
+	}
+	if o.PaperHeight > 0 {
+		params = params.WithPaperHeight(o.PaperHeight)
+	}
+
+	if o.MarginTop != nil {
+		params = params.WithMarginTop(*o.MarginTop)
+	}
+	if o.MarginBottom != nil {
+		params = params.WithMarginBottom(*o.MarginBottom)
+	}
+	if o.MarginLeft != nil {
+		params = params.WithMarginLeft(*o.MarginLeft)
+	}
+	if o.MarginRight != nil {
+		params = params.WithMarginRight(*o.MarginRight)
+	}
+
+	// 只设置页眉或页脚其中一个时，另一个使用空模板，避免出现chrome默认的页眉页脚
+	if o.HeaderTemplate != "" || o.FooterTemplate != "" {
+		header, footer := o.HeaderTemplate, o.FooterTemplate
+		if header == "" {
+			header = "<span></span>"
+		}
+		if footer == "" {
+			footer = "<span></span>"
+		}
+		params = params.WithDisplayHeaderFooter(true).
+			WithHeaderTemplate(header).
+			WithFooterTemplate(footer)
+	}
+	return params, nil
+}
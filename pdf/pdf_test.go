@@ -0,0 +1,65 @@
+package pdf
+
+import (
+	"github.com/LubyRuffy/chrome_proxy/models"
+	"github.com/chromedp/cdproto/page"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPrintParams(t *testing.T) {
+	zero := 0.0
+	tests := []struct {
+		name    string
+		options models.PDFOptions
+		want    *page.PrintToPDFParams
+		wantErr bool
+	}{
+		{
+			name:    "测试默认参数",
+			options: models.PDFOptions{},
+			want:    page.PrintToPDF(),
+		},
+		{
+			name: "测试纸张大小和边距",
+			options: models.PDFOptions{
+				PaperSize:       "A4",
+				Landscape:       true,
+				MarginTop:       &zero,
+				PrintBackground: true,
+			},
+			want: page.PrintToPDF().
+				WithLandscape(true).
+				WithPrintBackground(true).
+				WithPaperWidth(8.27).
+				WithPaperHeight(11.69).
+				WithMarginTop(0),
+		},
+		{
+			name: "测试页脚模板",
+			options: models.PDFOptions{
+				FooterTemplate: `<span class="pageNumber"></span>`,
+			},
+			want: page.PrintToPDF().
+				WithDisplayHeaderFooter(true).
+				WithHeaderTemplate("<span></span>").
+				WithFooterTemplate(`<span class="pageNumber"></span>`),
+		},
+		{
+			name:    "测试未知纸张",
+			options: models.PDFOptions{PaperSize: "b5"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := printParams(tt.options)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
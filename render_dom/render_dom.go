@@ -12,8 +12,27 @@ import (
 
 // RenderDom 生成单个url的 dom html
 func RenderDom(options *models.ChromeParam) (*models.RenderDomOutput, error) {
+	return RenderDomContext(context.Background(), options)
+}
+
+// RenderDomContext 生成单个url的 dom html，ctx 取消时中止
+func RenderDomContext(ctx context.Context, options *models.ChromeParam) (*models.RenderDomOutput, error) {
 	log.Println("RenderDom of url:", options.URL)
 
+	actions, finish := Actions(options)
+	out, err := chrome_action.ChromeActionsContext(ctx, options.ChromeActionInput, func(s string, i ...interface{}) {
+
+	}, options.Timeout, nil, actions...)
+
+	if err != nil {
+		return nil, fmt.Errorf("RenderDom failed(%w): %s", err, options.URL)
+	}
+	return finish(out)
+}
+
+// Actions 生成获取 dom 需要在页面中执行的动作，渲染完成后调用 finish 生成结果
+// 用于与其他动作共享同一次渲染
+func Actions(options *models.ChromeParam) ([]chromedp.Action, func(out *models.ChromeActionOutput) (*models.RenderDomOutput, error)) {
 	var html string
 	var actions []chromedp.Action
 
@@ -31,17 +50,13 @@ func RenderDom(options *models.ChromeParam) (*models.RenderDomOutput, error) {
 	var location string
 	actions = append(actions, chromedp.Location(&location))
 
-	err := chrome_action.ChromeActions(options.ChromeActionInput, func(s string, i ...interface{}) {
-
-	}, options.Timeout, nil, actions...)
+	return actions, func(out *models.ChromeActionOutput) (*models.RenderDomOutput, error) {
+		return &models.RenderDomOutput{
+			Html:     html,
+			Title:    title,
+			Location: location,
 
-	if err != nil {
-		return nil, fmt.Errorf("RenderDom failed(%w): %s", err, options.URL)
+			ChromeActionOutput: *out,
+		}, nil
 	}
-
-	return &models.RenderDomOutput{
-		Html:     html,
-		Title:    title,
-		Location: location,
-	}, nil
 }
@@ -3,30 +3,42 @@ package screenshot
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/LubyRuffy/chrome_proxy/chrome_action"
 	"github.com/LubyRuffy/chrome_proxy/models"
 	"github.com/LubyRuffy/chrome_proxy/utils"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
 	"github.com/chromedp/chromedp"
 	"log"
+	"math"
 	"os"
+	"strings"
 	"time"
 )
 
+// ErrElementNotFound 超时前没有找到需要截图的元素
+var ErrElementNotFound = errors.New("element not found")
+
 // ScreenshotURL 截图
 func ScreenshotURL(options *models.ChromeParam) (*models.ScreenshotOutput, error) {
-	log.Println("screenshot of url:", options.URL)
+	return ScreenshotURLContext(context.Background(), options)
+}
 
-	var buf []byte
-	var actions []chromedp.Action
-	actions = append(actions, chromedp.CaptureScreenshot(&buf))
+// ScreenshotURLContext 截图，ctx 取消时中止
+func ScreenshotURLContext(ctx context.Context, options *models.ChromeParam) (*models.ScreenshotOutput, error) {
+	log.Println("screenshot of url:", options.URL)
 
-	var title string
-	actions = append(actions, chromedp.Title(&title))
-	var url string
-	actions = append(actions, chromedp.Location(&url))
+	actions, finish, err := Actions(options)
+	if err != nil {
+		return nil, err
+	}
 
-	err := chrome_action.ChromeActions(options.ChromeActionInput, func(s string, i ...interface{}) {
+	out, err := chrome_action.ChromeActionsContext(ctx, options.ChromeActionInput, func(s string, i ...interface{}) {
 
 	}, options.Timeout, nil, actions...)
 	if err != nil {
@@ -34,25 +46,69 @@ func ScreenshotURL(options *models.ChromeParam) (*models.ScreenshotOutput, error
 	}
 
 	log.Printf("finished screenshot for %s", options.URL)
+	return finish(out)
+}
 
-	// 在截图中添加当前请求地址
-	if options.AddUrl {
-		tmp, err := AddUrlToTitle(options.URL, buf, options.AddTimeStamp)
-		if err != nil {
-			return nil, fmt.Errorf("add url title failed(%w): %s", err, options.URL)
-		}
-		buf = tmp
+// Actions 生成截图需要在页面中执行的动作，渲染完成后调用 finish 生成截图结果
+// 用于与其他动作共享同一次渲染
+func Actions(options *models.ChromeParam) ([]chromedp.Action, func(out *models.ChromeActionOutput) (*models.ScreenshotOutput, error), error) {
+	format, err := NormalizeFormat(options.Format)
+	if err != nil {
+		return nil, nil, err
 	}
+	options.Format = format
+
+	var buf, thumbnail []byte
+	var actions []chromedp.Action
+	actions = append(actions, Capture(options, &buf, &thumbnail))
+
+	var title string
+	actions = append(actions, chromedp.Title(&title))
+	var url string
+	actions = append(actions, chromedp.Location(&url))
+
+	return actions, func(out *models.ChromeActionOutput) (*models.ScreenshotOutput, error) {
+		// 在截图中添加当前请求地址
+		if options.AddUrl {
+			tmp, err := addUrlToTitle(options.URL, buf, options.AddTimeStamp, options.Format, options.Quality)
+			if err != nil {
+				return nil, fmt.Errorf("add url title failed(%w): %s", err, options.URL)
+			}
+			buf = tmp
+		}
+
+		return &models.ScreenshotOutput{
+			Data:      buf,
+			Title:     title,
+			Location:  url,
+			Format:    options.Format,
+			Thumbnail: thumbnail,
 
-	return &models.ScreenshotOutput{
-		Data:     buf,
-		Title:    title,
-		Location: url,
-	}, err
+			ChromeActionOutput: *out,
+		}, nil
+	}, nil
+}
+
+// NormalizeFormat 检查并规范化图片格式，为空时使用png
+func NormalizeFormat(format string) (string, error) {
+	switch strings.ToLower(format) {
+	case "", models.FormatPNG:
+		return models.FormatPNG, nil
+	case models.FormatJPEG, "jpg":
+		return models.FormatJPEG, nil
+	case models.FormatWebP:
+		return models.FormatWebP, nil
+	}
+	return "", fmt.Errorf("unsupported image format: %s", format)
 }
 
 // AddUrlToTitle 通过html转换对整个screenshot截图结果进行处理，添加标题栏并在其中写入访问的url地址
 func AddUrlToTitle(url string, picBuf []byte, hasTimeStamp bool) (result []byte, err error) {
+	return addUrlToTitle(url, picBuf, hasTimeStamp, models.FormatPNG, 0)
+}
+
+// addUrlToTitle 同 AddUrlToTitle，picBuf 和输出结果均为 format 格式
+func addUrlToTitle(url string, picBuf []byte, hasTimeStamp bool, format string, quality int) (result []byte, err error) {
 	htmlPart1 := `<!DOCTYPE html>
 <html lang="en">
 <head>
@@ -124,7 +180,7 @@ func AddUrlToTitle(url string, picBuf []byte, hasTimeStamp bool) (result []byte,
                 </div>
             </div>
             <div style="max-height:800px;overflow:hidden;">
-                <img  style="width:100%;" src="data:image/png;base64,`
+                <img  style="width:100%;" src="data:image/`
 	htmlPart3 := `" />
             </div>
         </div>
@@ -146,7 +202,8 @@ func AddUrlToTitle(url string, picBuf []byte, hasTimeStamp bool) (result []byte,
 	}
 
 	// 添加
-	html = append(append(append(html, []byte(htmlBase64)...), []byte(encodedBase64)...), []byte(htmlPart3)...)
+	html = append(append(html, []byte(htmlBase64)...), []byte(format+";base64,")...)
+	html = append(append(html, []byte(encodedBase64)...), []byte(htmlPart3)...)
 	var fn string
 	fn, err = utils.WriteTempFile(".html", func(f *os.File) error {
 		_, err = f.Write(html)
@@ -160,14 +217,183 @@ func AddUrlToTitle(url string, picBuf []byte, hasTimeStamp bool) (result []byte,
 	defer cancel()
 
 	var buf []byte
-	if err = chromedp.Run(ctx, FullScreenshot(`file://`+fn, 100, &buf)); err != nil {
+	if err = chromedp.Run(ctx, captureURL(`file://`+fn, format, quality, &buf)); err != nil {
 		return nil, err
 	}
 
 	return buf, err
 }
 
+// Capture 按照参数截图：元素截图、整页截图或者可视区域截图
+// 设置了 ThumbnailWidth 时，对同一区域额外生成一张缩略图
+func Capture(options *models.ChromeParam, res, thumbnail *[]byte) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		var clip page.Viewport
+		var err error
+		switch {
+		case options.Selector != "":
+			clip, err = elementClip(ctx, options.Selector, options.SelectorType, options.Padding)
+		case options.FullPage:
+			maxHeight := options.MaxHeight
+			if maxHeight <= 0 {
+				maxHeight = models.DefaultMaxHeight
+			}
+			clip, err = pageClip(ctx, maxHeight)
+		case options.ThumbnailWidth > 0:
+			clip, err = viewportClip(ctx)
+		}
+		if err != nil {
+			return err
+		}
+
+		params := captureParams(options.Format, options.Quality)
+		if options.Selector != "" || options.FullPage {
+			params = params.WithCaptureBeyondViewport(true).WithClip(&clip)
+		}
+		if *res, err = params.Do(ctx); err != nil {
+			return err
+		}
+
+		if options.ThumbnailWidth > 0 && thumbnail != nil {
+			thumbClip := thumbnailClip(clip, options.ThumbnailWidth)
+			*thumbnail, err = captureParams(options.Format, options.Quality).
+				WithCaptureBeyondViewport(true).
+				WithClip(&thumbClip).
+				Do(ctx)
+		}
+		return err
+	})
+}
+
+// captureParams 按照输出格式和质量生成截图参数，quality 只对 jpeg 和 webp 有效
+func captureParams(format string, quality int) *page.CaptureScreenshotParams {
+	params := page.CaptureScreenshot().WithFromSurface(true)
+	switch format {
+	case models.FormatJPEG:
+		params = params.WithFormat(page.CaptureScreenshotFormatJpeg)
+	case models.FormatWebP:
+		params = params.WithFormat(page.CaptureScreenshotFormatWebp)
+	default:
+		return params.WithFormat(page.CaptureScreenshotFormatPng)
+	}
+	if quality > 0 {
+		params = params.WithQuality(int64(quality))
+	}
+	return params
+}
+
+// elementClip 获取匹配 selector 的第一个元素的区域，四周额外保留 padding 像素
+// 元素在超时前没有出现时返回 ErrElementNotFound
+func elementClip(ctx context.Context, selector, selectorType string, padding int) (page.Viewport, error) {
+	by := chromedp.ByQuery
+	if selectorType == models.SelectorXPath {
+		by = chromedp.BySearch
+	}
+
+	var clip page.Viewport
+	var nodes []*cdp.Node
+	err := chromedp.Nodes(selector, &nodes, by, chromedp.NodeVisible).Do(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return clip, fmt.Errorf("%w: %s", ErrElementNotFound, selector)
+		}
+		return clip, err
+	}
+
+	obj, err := dom.ResolveNode().WithNodeID(nodes[0].NodeID).Do(ctx)
+	if err != nil {
+		return clip, err
+	}
+	ret, exp, err := runtime.CallFunctionOn(getClientRectJS).
+		WithObjectID(obj.ObjectID).
+		WithReturnByValue(true).
+		Do(ctx)
+	if err != nil {
+		return clip, err
+	}
+	if exp != nil {
+		return clip, exp
+	}
+
+	if err = json.Unmarshal(ret.Value, &clip); err != nil {
+		return clip, err
+	}
+	return paddingClip(clip, padding), nil
+}
+
+// getClientRectJS 获取元素相对于文档的位置和大小
+const getClientRectJS = `function() {
+	const e = this.getBoundingClientRect(),
+		t = this.ownerDocument.documentElement.getBoundingClientRect();
+	return {x: e.left - t.left, y: e.top - t.top, width: e.width, height: e.height};
+}`
+
+// paddingClip 四周扩展 padding 像素，并对齐到整数像素
+func paddingClip(clip page.Viewport, padding int) page.Viewport {
+	p := float64(padding)
+	x, y := math.Max(math.Floor(clip.X-p), 0), math.Max(math.Floor(clip.Y-p), 0)
+	return page.Viewport{
+		X:      x,
+		Y:      y,
+		Width:  math.Ceil(clip.X+clip.Width+p) - x,
+		Height: math.Ceil(clip.Y+clip.Height+p) - y,
+		Scale:  1,
+	}
+}
+
+// pageClip 获取整个页面的区域，高度超过 maxHeight 的部分会被裁掉
+func pageClip(ctx context.Context, maxHeight int) (page.Viewport, error) {
+	_, _, _, cssLayoutViewport, _, cssContentSize, err := page.GetLayoutMetrics().Do(ctx)
+	if err != nil {
+		return page.Viewport{}, err
+	}
+	return fullPageClip(cssLayoutViewport, cssContentSize, maxHeight), nil
+}
+
+// fullPageClip 计算整页截图的区域，宽度取可视区域宽度，高度取文档高度并限制在 maxHeight 以内
+func fullPageClip(viewport *page.LayoutViewport, content *dom.Rect, maxHeight int) page.Viewport {
+	width := float64(viewport.ClientWidth)
+	height := math.Ceil(content.Height)
+	if height < float64(viewport.ClientHeight) {
+		height = float64(viewport.ClientHeight)
+	}
+	if maxHeight > 0 && height > float64(maxHeight) {
+		height = float64(maxHeight)
+	}
+	return page.Viewport{
+		Width:  width,
+		Height: height,
+		Scale:  1,
+	}
+}
+
+// viewportClip 获取当前可视区域
+func viewportClip(ctx context.Context) (page.Viewport, error) {
+	_, _, _, cssLayoutViewport, _, _, err := page.GetLayoutMetrics().Do(ctx)
+	if err != nil {
+		return page.Viewport{}, err
+	}
+	return page.Viewport{
+		X:      float64(cssLayoutViewport.PageX),
+		Y:      float64(cssLayoutViewport.PageY),
+		Width:  float64(cssLayoutViewport.ClientWidth),
+		Height: float64(cssLayoutViewport.ClientHeight),
+		Scale:  1,
+	}, nil
+}
+
+// thumbnailClip 按照缩略图宽度计算缩放比例，只缩小不放大
+func thumbnailClip(clip page.Viewport, thumbnailWidth int) page.Viewport {
+	if clip.Width > float64(thumbnailWidth) {
+		clip.Scale = float64(thumbnailWidth) / clip.Width
+	} else {
+		clip.Scale = 1
+	}
+	return clip
+}
+
 // FullScreenshot takes a screenshot of the entire browser viewport.
+// 始终输出png，quality 仅为兼容保留；需要其他格式时使用 captureURL
 //
 // Note: chromedp.FullScreenshot overrides the device's emulation settings. Use
 // device.Reset to reset the emulation and viewport settings.
@@ -177,3 +403,15 @@ func FullScreenshot(urlstr string, quality int, res *[]byte) chromedp.Tasks {
 		chromedp.CaptureScreenshot(res),
 	}
 }
+
+// captureURL 打开 urlstr 并按照指定格式截取可视区域
+func captureURL(urlstr string, format string, quality int, res *[]byte) chromedp.Tasks {
+	return chromedp.Tasks{
+		chromedp.Navigate(urlstr),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			var err error
+			*res, err = captureParams(format, quality).Do(ctx)
+			return err
+		}),
+	}
+}
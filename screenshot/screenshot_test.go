@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"github.com/LubyRuffy/chrome_proxy/models"
 	"github.com/LubyRuffy/chrome_proxy/utils"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/cdproto/page"
 	"github.com/chromedp/chromedp"
 	"github.com/stretchr/testify/assert"
 	"log"
@@ -88,3 +90,135 @@ func TestScreenshotURL(t *testing.T) {
 		})
 	}
 }
+
+func TestFullPageClip(t *testing.T) {
+	type args struct {
+		viewport  *page.LayoutViewport
+		content   *dom.Rect
+		maxHeight int
+	}
+	tests := []struct {
+		name string
+		args args
+		want page.Viewport
+	}{
+		{
+			name: "测试整页高度",
+			args: args{
+				viewport:  &page.LayoutViewport{ClientWidth: 1024, ClientHeight: 768},
+				content:   &dom.Rect{Width: 1024, Height: 3000.5},
+				maxHeight: 10000,
+			},
+			want: page.Viewport{Width: 1024, Height: 3001, Scale: 1},
+		},
+		{
+			name: "测试超过最大高度",
+			args: args{
+				viewport:  &page.LayoutViewport{ClientWidth: 1024, ClientHeight: 768},
+				content:   &dom.Rect{Width: 1024, Height: 50000},
+				maxHeight: 10000,
+			},
+			want: page.Viewport{Width: 1024, Height: 10000, Scale: 1},
+		},
+		{
+			name: "测试页面比可视区域短",
+			args: args{
+				viewport:  &page.LayoutViewport{ClientWidth: 1024, ClientHeight: 768},
+				content:   &dom.Rect{Width: 1024, Height: 100},
+				maxHeight: 10000,
+			},
+			want: page.Viewport{Width: 1024, Height: 768, Scale: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, fullPageClip(tt.args.viewport, tt.args.content, tt.args.maxHeight))
+		})
+	}
+}
+
+func TestPaddingClip(t *testing.T) {
+	type args struct {
+		clip    page.Viewport
+		padding int
+	}
+	tests := []struct {
+		name string
+		args args
+		want page.Viewport
+	}{
+		{
+			name: "测试无padding",
+			args: args{clip: page.Viewport{X: 10.5, Y: 20.2, Width: 100, Height: 50}},
+			want: page.Viewport{X: 10, Y: 20, Width: 101, Height: 51, Scale: 1},
+		},
+		{
+			name: "测试padding",
+			args: args{clip: page.Viewport{X: 100, Y: 200, Width: 100, Height: 50}, padding: 10},
+			want: page.Viewport{X: 90, Y: 190, Width: 120, Height: 70, Scale: 1},
+		},
+		{
+			name: "测试padding超出页面左上角",
+			args: args{clip: page.Viewport{X: 5, Y: 0, Width: 100, Height: 50}, padding: 10},
+			want: page.Viewport{X: 0, Y: 0, Width: 115, Height: 60, Scale: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, paddingClip(tt.args.clip, tt.args.padding))
+		})
+	}
+}
+
+func TestThumbnailClip(t *testing.T) {
+	tests := []struct {
+		name           string
+		clip           page.Viewport
+		thumbnailWidth int
+		wantScale      float64
+	}{
+		{
+			name:           "测试缩小",
+			clip:           page.Viewport{Width: 1024, Height: 768, Scale: 1},
+			thumbnailWidth: 256,
+			wantScale:      0.25,
+		},
+		{
+			name:           "测试不放大",
+			clip:           page.Viewport{Width: 200, Height: 100, Scale: 1},
+			thumbnailWidth: 256,
+			wantScale:      1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := thumbnailClip(tt.clip, tt.thumbnailWidth)
+			assert.Equal(t, tt.wantScale, got.Scale)
+			assert.Equal(t, tt.clip.Width, got.Width)
+		})
+	}
+}
+
+func TestNormalizeFormat(t *testing.T) {
+	tests := []struct {
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{format: "", want: models.FormatPNG},
+		{format: "JPG", want: models.FormatJPEG},
+		{format: "webp", want: models.FormatWebP},
+		{format: "gif", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			got, err := NormalizeFormat(tt.format)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
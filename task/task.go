@@ -0,0 +1,240 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/LubyRuffy/chrome_proxy/archive"
+	"github.com/LubyRuffy/chrome_proxy/callback"
+	"github.com/LubyRuffy/chrome_proxy/chrome_action"
+	"github.com/LubyRuffy/chrome_proxy/extract"
+	"github.com/LubyRuffy/chrome_proxy/models"
+	"github.com/LubyRuffy/chrome_proxy/pdf"
+	"github.com/LubyRuffy/chrome_proxy/render_dom"
+	"github.com/LubyRuffy/chrome_proxy/screenshot"
+	"github.com/chromedp/chromedp"
+	"log"
+)
+
+// Valid 判断是否为支持的渲染动作
+func Valid(action string) bool {
+	switch action {
+	case models.ActionScreenshot, models.ActionDom, models.ActionPDF, models.ActionArchive, models.ActionExtract:
+		return true
+	}
+	return false
+}
+
+// actionNames 渲染失败时错误信息中的动作名称，与各个包单独渲染时一致
+var actionNames = map[string]string{
+	models.ActionScreenshot: "screenShot",
+	models.ActionDom:        "RenderDom",
+	models.ActionPDF:        "PrintPDF",
+	models.ActionArchive:    "Archive",
+	models.ActionExtract:    "Extract",
+}
+
+// Run 执行单个渲染动作，返回统一输出结果，ctx 取消时中止渲染
+// 设置了回调地址时，在后台将结果发送到回调地址
+func Run(ctx context.Context, action string, options *models.ChromeParam) models.Result {
+	var result models.Result
+	switch action {
+	case models.ActionScreenshot:
+		out, err := screenshot.ScreenshotURLContext(ctx, options)
+		if err != nil {
+			result = errorResult(options.URL, err)
+			break
+		}
+		result = out.Result(options.URL)
+	case models.ActionDom:
+		out, err := render_dom.RenderDomContext(ctx, options)
+		if err != nil {
+			result = errorResult(options.URL, err)
+			break
+		}
+		result = out.Result(options.URL)
+	case models.ActionPDF:
+		out, err := pdf.PrintPDFContext(ctx, options)
+		if err != nil {
+			result = errorResult(options.URL, err)
+			break
+		}
+		result = out.Result(options.URL)
+	case models.ActionArchive:
+		out, err := archive.ArchiveContext(ctx, options)
+		if err != nil {
+			result = errorResult(options.URL, err)
+			break
+		}
+		result = out.Result(options.URL)
+	case models.ActionExtract:
+		out, err := extract.ExtractContext(ctx, options)
+		if err != nil {
+			result = errorResult(options.URL, err)
+			break
+		}
+		result = out.Result(options.URL)
+	default:
+		result = models.Result{Code: 400, Url: options.URL, Message: fmt.Sprintf("unknown action: %s", action)}
+	}
+
+	result.Action = action
+	sendCallback(ctx, options, result)
+	return result
+}
+
+// RunAll 在同一次渲染中执行多个动作，每个 url 只打开一次页面
+// 单个动作失败不影响其他动作，渲染本身失败时所有动作都失败
+// 渲染过程中收集的信息（跳转链、HAR 等）只在返回的 TargetResult 中出现一次
+func RunAll(ctx context.Context, actions []string, options *models.ChromeParam) models.TargetResult {
+	target := models.TargetResult{Url: options.URL, Results: make([]models.Result, len(actions))}
+
+	var captures []*capture
+	var chromeActions []chromedp.Action
+	for i, action := range actions {
+		c, err := newCapture(action, options)
+		if err != nil {
+			target.Results[i] = errorResult(options.URL, err)
+			target.Results[i].Action = action
+			continue
+		}
+		c.index = i
+		captures = append(captures, c)
+
+		// 动作失败时记录错误并继续执行后面的动作
+		chromeActions = append(chromeActions, chromedp.ActionFunc(func(ctx context.Context) error {
+			c.err = chromedp.Tasks(c.actions).Do(ctx)
+			return nil
+		}))
+	}
+
+	if len(captures) > 0 {
+		out, err := chrome_action.ChromeActionsContext(ctx, options.ChromeActionInput, func(s string, i ...interface{}) {
+
+		}, options.Timeout, nil, chromeActions...)
+		if out != nil {
+			target.ChromeActionOutput = *out
+		}
+
+		for _, c := range captures {
+			var result models.Result
+			switch {
+			case err != nil:
+				result = errorResult(options.URL, fmt.Errorf("%s failed(%w): %s", actionNames[c.action], err, options.URL))
+			case c.err != nil:
+				result = errorResult(options.URL, fmt.Errorf("%s failed(%w): %s", actionNames[c.action], c.err, options.URL))
+			default:
+				result = c.result(&models.ChromeActionOutput{})
+				result.ScriptSuccess = target.ScriptResult != nil
+			}
+			result.Action = c.action
+			target.Results[c.index] = result
+		}
+	}
+
+	for _, result := range target.Results {
+		sendCallback(ctx, options, result)
+	}
+	return target
+}
+
+// capture 单个动作在共享渲染中需要执行的动作，以及渲染完成后生成结果的方法
+type capture struct {
+	index   int
+	action  string
+	actions []chromedp.Action
+	result  func(out *models.ChromeActionOutput) models.Result
+	err     error
+}
+
+// newCapture 检查动作参数并生成需要在页面中执行的动作，每个动作使用独立的参数副本
+func newCapture(action string, options *models.ChromeParam) (*capture, error) {
+	o := *options
+	c := &capture{action: action}
+	switch action {
+	case models.ActionScreenshot:
+		actions, finish, err := screenshot.Actions(&o)
+		if err != nil {
+			return nil, err
+		}
+		c.actions = actions
+		c.result = func(out *models.ChromeActionOutput) models.Result {
+			r, err := finish(out)
+			if err != nil {
+				return errorResult(o.URL, err)
+			}
+			return r.Result(o.URL)
+		}
+	case models.ActionDom:
+		actions, finish := render_dom.Actions(&o)
+		c.actions = actions
+		c.result = func(out *models.ChromeActionOutput) models.Result {
+			r, err := finish(out)
+			if err != nil {
+				return errorResult(o.URL, err)
+			}
+			return r.Result(o.URL)
+		}
+	case models.ActionPDF:
+		actions, finish, err := pdf.Actions(&o)
+		if err != nil {
+			return nil, err
+		}
+		c.actions = actions
+		c.result = func(out *models.ChromeActionOutput) models.Result {
+			r, err := finish(out)
+			if err != nil {
+				return errorResult(o.URL, err)
+			}
+			return r.Result(o.URL)
+		}
+	case models.ActionArchive:
+		actions, finish, err := archive.Actions(&o)
+		if err != nil {
+			return nil, err
+		}
+		c.actions = actions
+		c.result = func(out *models.ChromeActionOutput) models.Result {
+			r, err := finish(out)
+			if err != nil {
+				return errorResult(o.URL, err)
+			}
+			return r.Result(o.URL)
+		}
+	case models.ActionExtract:
+		actions, finish := extract.Actions(&o)
+		c.actions = actions
+		c.result = func(out *models.ChromeActionOutput) models.Result {
+			r, err := finish(out)
+			if err != nil {
+				return errorResult(o.URL, err)
+			}
+			return r.Result(o.URL)
+		}
+	default:
+		return nil, fmt.Errorf("unknown action: %s", action)
+	}
+	return c, nil
+}
+
+// errorResult 将渲染错误转换为统一输出结果，元素不存在时为404
+func errorResult(url string, err error) models.Result {
+	code := 500
+	if errors.Is(err, screenshot.ErrElementNotFound) {
+		code = 404
+	}
+	return models.Result{Code: code, Url: url, Message: err.Error()}
+}
+
+// sendCallback 设置了回调地址时，在后台将结果发送到回调地址，取消的任务不再回调
+func sendCallback(ctx context.Context, options *models.ChromeParam, result models.Result) {
+	if options.CallbackURL == "" || ctx.Err() != nil {
+		return
+	}
+	go func() {
+		err := callback.DefaultSender.Send(context.Background(), options.CallbackURL, result)
+		if err != nil {
+			log.Printf("[WARNING] callback of %s failed: %s", options.URL, err)
+		}
+	}()
+}
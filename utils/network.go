@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"github.com/LubyRuffy/chrome_proxy/models"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 func GetOptionFromRequest(r *http.Request) (*models.ChromeParam, error) {
@@ -19,3 +22,106 @@ func GetOptionFromRequest(r *http.Request) (*models.ChromeParam, error) {
 	}
 	return &options, nil
 }
+
+// acceptFormats Accept 中的媒体类型对应的输出格式
+var acceptFormats = map[string][]string{
+	"image/png":       {models.FormatPNG},
+	"image/jpeg":      {models.FormatJPEG},
+	"image/webp":      {models.FormatWebP},
+	"application/pdf": {models.FormatPDF},
+	"image/*":         {models.FormatPNG, models.FormatJPEG, models.FormatWebP},
+	"*/*":             {models.FormatPNG, models.FormatJPEG, models.FormatWebP, models.FormatPDF},
+}
+
+// RawFormats 判断请求是否需要直接返回文件内容而不是 json
+// 请求带有 raw=1 参数，或者 Accept 头中包含图片、pdf 类型时 raw 为 true，*/* 不会触发
+// formats 为 Accept 中可以接受的格式，按 q 值从高到低排列，q=0 的类型不可接受；为空时不限制格式
+func RawFormats(r *http.Request) (formats []string, raw bool) {
+	if v := r.URL.Query().Get("raw"); v == "1" || v == "true" {
+		raw = true
+	}
+
+	type accepted struct {
+		formats []string
+		q       float64
+	}
+	var list []accepted
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		parts := strings.Split(accept, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(parts[0]))
+		f, ok := acceptFormats[mediaType]
+		if !ok {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range parts[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) == 2 && strings.TrimSpace(kv[0]) == "q" {
+				if v, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		if mediaType != "*/*" {
+			raw = true
+		}
+		list = append(list, accepted{formats: f, q: q})
+	}
+	if !raw {
+		return nil, false
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].q > list[j].q
+	})
+	seen := make(map[string]bool)
+	for _, a := range list {
+		for _, f := range a.formats {
+			if !seen[f] {
+				seen[f] = true
+				formats = append(formats, f)
+			}
+		}
+	}
+	return formats, raw
+}
+
+// NegotiateFormat 按顺序从 Accept 的格式中选择 action 可以输出的第一个格式
+// format 为请求参数中指定的格式，指定时必须在 Accept 中；formats 为空时不限制，直接返回 format
+// 没有可以输出的格式时返回 false
+func NegotiateFormat(action string, formats []string, format string) (string, bool) {
+	format = strings.ToLower(format)
+	if format == "jpg" {
+		format = models.FormatJPEG
+	}
+	if len(formats) == 0 {
+		return format, true
+	}
+	for _, f := range formats {
+		if !FormatAcceptable(action, f) {
+			continue
+		}
+		if format == "" || f == format {
+			return f, true
+		}
+	}
+	return "", false
+}
+
+// FormatAcceptable 判断 RawFormats 返回的格式是否为该动作可以输出的格式，format 为空时不限制
+func FormatAcceptable(action string, format string) bool {
+	if format == "" {
+		return true
+	}
+	switch action {
+	case models.ActionScreenshot:
+		return format == models.FormatPNG || format == models.FormatJPEG || format == models.FormatWebP
+	case models.ActionPDF:
+		return format == models.FormatPDF
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"github.com/LubyRuffy/chrome_proxy/models"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRawFormats(t *testing.T) {
+	tests := []struct {
+		name        string
+		target      string
+		accept      string
+		wantFormats []string
+		wantRaw     bool
+	}{
+		{
+			name:   "测试默认json",
+			target: "/screenshot",
+			accept: "application/json",
+		},
+		{
+			name:   "测试浏览器默认Accept",
+			target: "/screenshot",
+			accept: "text/html, */*;q=0.8",
+		},
+		{
+			name:    "测试raw参数",
+			target:  "/screenshot?raw=1",
+			wantRaw: true,
+		},
+		{
+			name:        "测试Accept图片格式",
+			target:      "/screenshot",
+			accept:      "image/webp;q=0.9, application/json",
+			wantFormats: []string{models.FormatWebP},
+			wantRaw:     true,
+		},
+		{
+			name:        "测试Accept多个类型",
+			target:      "/pdf",
+			accept:      "image/png, application/pdf",
+			wantFormats: []string{models.FormatPNG, models.FormatPDF},
+			wantRaw:     true,
+		},
+		{
+			name:        "测试按q值排序",
+			target:      "/screenshot",
+			accept:      "image/png;q=0.5, image/jpeg, image/webp;q=0",
+			wantFormats: []string{models.FormatJPEG, models.FormatPNG},
+			wantRaw:     true,
+		},
+		{
+			name:        "测试Accept任意图片",
+			target:      "/screenshot",
+			accept:      "image/*, */*;q=0.1",
+			wantFormats: []string{models.FormatPNG, models.FormatJPEG, models.FormatWebP, models.FormatPDF},
+			wantRaw:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", tt.target, nil)
+			r.Header.Set("Accept", tt.accept)
+			formats, raw := RawFormats(r)
+			assert.Equal(t, tt.wantFormats, formats)
+			assert.Equal(t, tt.wantRaw, raw)
+		})
+	}
+}
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		action  string
+		formats []string
+		format  string
+		want    string
+		wantOk  bool
+	}{
+		{name: "测试不限制格式", action: models.ActionScreenshot, format: "jpg", want: models.FormatJPEG, wantOk: true},
+		{name: "测试pdf跳过图片", action: models.ActionPDF, formats: []string{models.FormatPNG, models.FormatPDF}, want: models.FormatPDF, wantOk: true},
+		{name: "测试pdf只接受图片", action: models.ActionPDF, formats: []string{models.FormatPNG, models.FormatJPEG, models.FormatWebP}, wantOk: false},
+		{name: "测试截图选第一个", action: models.ActionScreenshot, formats: []string{models.FormatPDF, models.FormatWebP, models.FormatPNG}, want: models.FormatWebP, wantOk: true},
+		{name: "测试参数格式可接受", action: models.ActionScreenshot, formats: []string{models.FormatPNG, models.FormatJPEG}, format: "jpeg", want: models.FormatJPEG, wantOk: true},
+		{name: "测试参数格式不可接受", action: models.ActionScreenshot, formats: []string{models.FormatPNG}, format: "jpeg", wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := NegotiateFormat(tt.action, tt.formats, tt.format)
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFormatAcceptable(t *testing.T) {
+	tests := []struct {
+		name   string
+		action string
+		format string
+		want   bool
+	}{
+		{name: "测试不限制格式", action: models.ActionScreenshot, format: "", want: true},
+		{name: "测试截图图片格式", action: models.ActionScreenshot, format: models.FormatWebP, want: true},
+		{name: "测试截图请求pdf", action: models.ActionScreenshot, format: models.FormatPDF, want: false},
+		{name: "测试pdf", action: models.ActionPDF, format: models.FormatPDF, want: true},
+		{name: "测试pdf请求图片", action: models.ActionPDF, format: models.FormatPNG, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, FormatAcceptable(tt.action, tt.format))
+		})
+	}
+}